@@ -1,12 +1,30 @@
 // @title       Subscription API
 // @version     1.0.0
 // @description CRUDL service for user subscriptions
+// @description Every request acts within one tenant: the one named by the caller's credentials, or the default tenant if they name none.
+// @description An X-Tenant-ID header may repeat the tenant, and picks it when authentication is disabled.
+// @description Users act in a role taken from the token's roles claim, or else assigned to them in the tenant: admins manage every user's subscriptions,
+// @description analysts may only aggregate and export, across users, and members work with their own subscriptions. Refused actions get 403.
+// @description Each client has separate rate limits for reads, writes and cost reports; responses carry RateLimit-* headers and a 429 says when to retry.
+// @description Every response carries an X-Request-ID, the client's own if it sent one, which tags all log lines of the request.
 // @host        localhost:8080
 
+// @securityDefinitions.apikey BearerAuth
+// @in                         header
+// @name                       Authorization
+// @description                JWT as "Bearer <token>"; the sub claim is the caller's user ID
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in                         header
+// @name                       Authorization
+// @description                API key of a service-to-service caller as "ApiKey <key>"
+
 package main
 
 import (
+	"bytes"
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -17,9 +35,14 @@ import (
 
 	"github.com/Neroframe/sub_crudl/config"
 	"github.com/Neroframe/sub_crudl/internal/app"
+	"github.com/Neroframe/sub_crudl/internal/infra/fx"
+	"github.com/Neroframe/sub_crudl/internal/infra/memory"
+	"github.com/Neroframe/sub_crudl/internal/infra/metrics"
 	"github.com/Neroframe/sub_crudl/internal/infra/postgres"
 	httpapi "github.com/Neroframe/sub_crudl/internal/interfaces/http"
+	"github.com/Neroframe/sub_crudl/pkg/jwt"
 	"github.com/Neroframe/sub_crudl/pkg/logger"
+	"github.com/Neroframe/sub_crudl/pkg/ratelimit"
 
 	_ "github.com/Neroframe/sub_crudl/docs"
 	"github.com/gin-gonic/gin"
@@ -31,7 +54,10 @@ import (
 )
 
 func main() {
-	cfg, err := config.Load("config/dev.yaml")
+	configPath := flag.String("config", "config/dev.yaml", "path of the YAML config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -39,36 +65,98 @@ func main() {
 	log := logger.New(logger.Config(cfg.Log))
 	log.Info("config loaded", "version", cfg.Version)
 
-	// Connect to Postgres with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	db, err := sqlx.ConnectContext(ctx, "postgres", postgres.BuildDSN(cfg.Postgres))
-	if err != nil {
-		log.Fatal("db connect failed", "err", err)
-	}
+	// Prometheus metrics, served on /metrics
+	stats := metrics.New()
 
-	// Ping to verify conn
-	if err := db.PingContext(ctx); err != nil {
-		log.Fatal("db ping failed", "err", err)
+	// Pick the storage backend
+	var repo app.SubscriptionRepository
+	var keyRepo app.APIKeyRepository
+	var roleRepo app.UserRoleRepository
+	var checks []httpapi.ReadinessCheck
+	switch cfg.Storage.Driver {
+	case "", "postgres":
+		db := connectPostgres(cfg.Postgres, log)
+		defer db.Close()
+		stats.RegisterDB(db.DB, cfg.Postgres.DBName)
+		repo = postgres.NewSubscriptionRepo(db.DB)
+		keyRepo = postgres.NewAPIKeyRepo(db.DB)
+		roleRepo = postgres.NewUserRoleRepo(db.DB)
+
+		schemaVersion, err := postgres.SchemaVersion()
+		if err != nil {
+			log.Fatal("schema version lookup failed", "err", err)
+		}
+		checks = append(checks, httpapi.ReadinessCheck{Name: "postgres", Fn: postgres.ReadinessCheck(db.DB, schemaVersion)})
+	case "memory":
+		log.Warn("using in-memory storage, data will be lost on restart")
+		repo = memory.NewSubscriptionRepo()
+		keyRepo = memory.NewAPIKeyRepo()
+		roleRepo = memory.NewUserRoleRepo()
+	default:
+		log.Fatal("unknown storage driver", "driver", cfg.Storage.Driver)
 	}
 
-	// Apply pool settings
-	db.SetMaxOpenConns(cfg.Postgres.MaxOpenConns)
-	db.SetMaxIdleConns(cfg.Postgres.MaxIdleConns)
-	db.SetConnMaxLifetime(cfg.Postgres.ConnMaxLifetime)
-	defer db.Close()
+	// Load exchange rates for multi-currency aggregation
+	rates, err := fx.LoadCSV(cfg.FX.RatesFile)
+	if err != nil {
+		log.Fatal("exchange rates load failed", "err", err)
+	}
 
 	// Wire layers
-	// repo := postgres.NewSubscriptionRepo(db, log)
-	repo := postgres.NewSubscriptionRepo(db.DB)
-	service := app.NewSubscriptionService(repo, log)
+	service := stats.InstrumentService(app.NewSubscriptionService(repo, rates, log))
+	keyService := app.NewAPIKeyService(keyRepo, log)
+	roleService := app.NewRoleService(roleRepo, log)
 	h := httpapi.NewHandler(service, log)
+	keys := httpapi.NewAPIKeyHandler(keyService, log)
+
+	verifier, err := newJWTVerifier(cfg.Auth.JWT)
+	if err != nil {
+		log.Fatal("jwt verifier setup failed", "err", err)
+	}
+	switch {
+	case verifier == nil && !cfg.Auth.Disabled:
+		log.Fatal("no jwt algorithm configured: set auth.jwt, or auth.disabled for local development")
+	case verifier == nil:
+		log.Warn("authentication is disabled, every caller may act on any subscription")
+	case cfg.Auth.Disabled:
+		log.Fatal("auth.disabled is set together with auth.jwt; pick one")
+	}
+	health := httpapi.NewHealthHandler(cfg.HTTP.ReadinessTimeout, log, checks...)
+	auth := httpapi.NewAuthenticator(verifier, keyService, roleService, log)
+	limits := httpapi.NewRateLimiter(
+		newLimiter(cfg.HTTP.RateLimit.IP),
+		newLimiter(cfg.HTTP.RateLimit.Read),
+		newLimiter(cfg.HTTP.RateLimit.Write),
+		newLimiter(cfg.HTTP.RateLimit.Aggregate),
+		log,
+	)
+
+	// Background jobs, stopped on shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if cfg.SoftDelete.PurgeInterval > 0 {
+		go app.RunTrashPurger(jobsCtx, service, cfg.SoftDelete.Retention, cfg.SoftDelete.PurgeInterval, log)
+	}
+	if cfg.Idempotency.PurgeInterval > 0 {
+		go app.RunIdempotencyPurger(jobsCtx, service, cfg.Idempotency.TTL, cfg.Idempotency.PurgeInterval, log)
+	}
 
 	// Gin setup
-	router := gin.Default()
-	httpapi.RegisterRoutes(router, h)
+	router := gin.New()
+	// Client IPs feed the rate limiter, so only listed proxies may forward them
+	if err := router.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		log.Fatal("invalid http.trustedProxies", "err", err)
+	}
+	router.Use(
+		httpapi.RequestIDMiddleware(log),
+		httpapi.AccessLogMiddleware(log),
+		stats.Middleware(),
+		gin.Recovery(),
+	)
+	httpapi.RegisterRoutes(router, h, keys, auth, limits, health)
 	// Init swagger
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/metrics", gin.WrapH(stats.Handler()))
 
 	addr := fmt.Sprintf("%s:%d", cfg.HTTP.Host, cfg.HTTP.Port)
 	srv := &http.Server{
@@ -92,7 +180,14 @@ func main() {
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 
+	// Fail readiness first so load balancers stop routing here, then let
+	// in-flight requests finish
+	health.Drain()
+	log.Info("draining before shutdown", "delay", cfg.HTTP.DrainDelay)
+	time.Sleep(cfg.HTTP.DrainDelay)
+
 	log.Info("shutting down server...")
+	stopJobs()
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
 
@@ -102,3 +197,76 @@ func main() {
 		log.Info("server stopped cleanly")
 	}
 }
+
+func connectPostgres(cfg config.Postgres, log *logger.Logger) *sqlx.DB {
+	// Connect to Postgres with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db, err := sqlx.ConnectContext(ctx, "postgres", postgres.BuildDSN(cfg))
+	if err != nil {
+		log.Fatal("db connect failed", "err", err)
+	}
+
+	// Ping to verify conn
+	if err := db.PingContext(ctx); err != nil {
+		log.Fatal("db ping failed", "err", err)
+	}
+
+	// Apply pool settings
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return db
+}
+
+// newJWTVerifier builds the token verifier from config. It returns nil when
+// no algorithm is configured, which main only accepts with auth.disabled.
+func newJWTVerifier(cfg config.JWT) (*jwt.Verifier, error) {
+	var v *jwt.Verifier
+	switch cfg.Algorithm {
+	case "":
+		return nil, nil
+	case jwt.HS256:
+		secret := []byte(cfg.Secret)
+		if cfg.SecretFile != "" {
+			b, err := os.ReadFile(cfg.SecretFile)
+			if err != nil {
+				return nil, fmt.Errorf("read jwt secret: %w", err)
+			}
+			secret = bytes.TrimSpace(b)
+		}
+		hs, err := jwt.NewHS256(secret)
+		if err != nil {
+			return nil, err
+		}
+		v = hs
+	case jwt.RS256:
+		b, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read jwt public key: %w", err)
+		}
+		key, err := jwt.ParseRSAPublicKeyPEM(b)
+		if err != nil {
+			return nil, err
+		}
+		rs, err := jwt.NewRS256(key)
+		if err != nil {
+			return nil, err
+		}
+		v = rs
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", cfg.Algorithm)
+	}
+	v.Issuer = cfg.Issuer
+	v.Audience = cfg.Audience
+	v.Leeway = cfg.Leeway
+	return v, nil
+}
+
+// newLimiter builds the limiter of one budget, or nil when it is disabled.
+func newLimiter(b config.Budget) *ratelimit.Limiter {
+	if b.PerMinute <= 0 {
+		return nil
+	}
+	return ratelimit.New(b.PerMinute, b.Burst)
+}
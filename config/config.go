@@ -11,9 +11,14 @@ type (
 	Config struct {
 		Version string `yaml:"version"`
 
-		HTTP     HTTP     `yaml:"http"`
-		Postgres Postgres `yaml:"postgres"`
-		Log      Log      `yaml:"log"`
+		HTTP        HTTP        `yaml:"http"`
+		Storage     Storage     `yaml:"storage"`
+		Postgres    Postgres    `yaml:"postgres"`
+		Log         Log         `yaml:"log"`
+		FX          FX          `yaml:"fx"`
+		SoftDelete  SoftDelete  `yaml:"softDelete"`
+		Idempotency Idempotency `yaml:"idempotency"`
+		Auth        Auth        `yaml:"auth"`
 	}
 
 	HTTP struct {
@@ -22,6 +27,31 @@ type (
 		ReadTimeout  time.Duration `yaml:"readTimeout"`
 		WriteTimeout time.Duration `yaml:"writeTimeout"`
 		IdleTimeout  time.Duration `yaml:"idleTimeout"`
+		RateLimit    RateLimit     `yaml:"rateLimit"`
+
+		// TrustedProxies lists the addresses or CIDRs allowed to set
+		// X-Forwarded-For. Empty means the peer address is the client IP.
+		TrustedProxies []string `yaml:"trustedProxies"`
+
+		ReadinessTimeout time.Duration `yaml:"readinessTimeout"` // for the dependency checks of /readyz
+		DrainDelay       time.Duration `yaml:"drainDelay"`       // /readyz fails this long before shutdown
+	}
+
+	// RateLimit budgets requests per client: API key, token subject or IP.
+	RateLimit struct {
+		IP        Budget `yaml:"ip"` // every request, before authentication
+		Read      Budget `yaml:"read"`
+		Write     Budget `yaml:"write"`
+		Aggregate Budget `yaml:"aggregate"` // /subscriptions/aggregate and its time series
+	}
+
+	Budget struct {
+		PerMinute int `yaml:"perMinute"` // sustained rate; 0 disables the limit
+		Burst     int `yaml:"burst"`     // requests allowed at once
+	}
+
+	Storage struct {
+		Driver string `yaml:"driver"` // "postgres" (default) or "memory"
 	}
 
 	Postgres struct {
@@ -35,6 +65,35 @@ type (
 		ConnMaxLifetime time.Duration `yaml:"connMaxLifetime"`
 	}
 
+	FX struct {
+		RatesFile string `yaml:"ratesFile"` // CSV with "currency,rate" rows
+	}
+
+	SoftDelete struct {
+		Retention     time.Duration `yaml:"retention"`     // how long deleted subscriptions stay in the trash
+		PurgeInterval time.Duration `yaml:"purgeInterval"` // 0 disables the purger
+	}
+
+	Idempotency struct {
+		TTL           time.Duration `yaml:"ttl"`           // how long a key is remembered
+		PurgeInterval time.Duration `yaml:"purgeInterval"` // 0 disables the purger
+	}
+
+	Auth struct {
+		JWT      JWT  `yaml:"jwt"`
+		Disabled bool `yaml:"disabled"` // lets every caller act as an admin without credentials; local development only
+	}
+
+	JWT struct {
+		Algorithm     string        `yaml:"algorithm"`     // "HS256", "RS256"; required unless auth.disabled
+		Secret        string        `yaml:"secret"`        // HS256 shared secret
+		SecretFile    string        `yaml:"secretFile"`    // HS256 secret read from a file instead
+		PublicKeyFile string        `yaml:"publicKeyFile"` // RS256 PEM public key
+		Issuer        string        `yaml:"issuer"`        // required iss, if set
+		Audience      string        `yaml:"audience"`      // required aud, if set
+		Leeway        time.Duration `yaml:"leeway"`        // clock skew tolerated on exp and nbf
+	}
+
 	Log struct {
 		Level        string `yaml:"level"`        // "debug", "info", "warn", "error"
 		Format       string `yaml:"format"`       // "text" or "json"
@@ -0,0 +1,21 @@
+package app
+
+import "context"
+
+// UnknownActor is recorded when a mutation carries no caller identity.
+const UnknownActor = "unknown"
+
+type actorKey struct{}
+
+// WithActor attaches the identity of the caller to ctx for the audit trail.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the caller set by WithActor, or UnknownActor.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return UnknownActor
+}
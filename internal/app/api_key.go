@@ -0,0 +1,21 @@
+package app
+
+import (
+	"context"
+
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// APIKeyService issues and checks the keys of service-to-service callers.
+type APIKeyService interface {
+	// Issue creates a key and returns it with its secret, which is not
+	// stored and cannot be retrieved later.
+	Issue(ctx context.Context, input appdto.IssueAPIKeyInput) (key *domain.APIKey, secret string, err error)
+	List(ctx context.Context) ([]*domain.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	// Authenticate returns the active key with the given secret, or
+	// ErrInvalidAPIKey.
+	Authenticate(ctx context.Context, secret string) (*domain.APIKey, error)
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// APIKeyRepository stores API keys by the hash of their secret. Keys are
+// listed and revoked within the tenant of the context, but looked up by hash
+// across tenants, since authentication is what finds the tenant.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *domain.APIKey, hash string) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) // ErrAPIKeyNotFound if unknown
+	// ListAPIKeys returns every key, revoked and expired ones included,
+	// newest first.
+	ListAPIKeys(ctx context.Context) ([]*domain.APIKey, error)
+	// RevokeAPIKey returns ErrAPIKeyNotFound unless the key exists and has
+	// not been revoked yet.
+	RevokeAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/google/uuid"
+)
+
+const cursorDateLayout = "2006-01-02"
+
+// encodeCursor turns a keyset into an opaque token for clients.
+func encodeCursor(k appdto.Keyset) string {
+	raw := k.StartDate.Format(cursorDateLayout) + "|" + k.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a token produced by encodeCursor.
+func decodeCursor(cursor string) (*appdto.Keyset, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalidField("cursor", "is malformed")
+	}
+
+	dateStr, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, invalidField("cursor", "is malformed")
+	}
+	startDate, err := time.Parse(cursorDateLayout, dateStr)
+	if err != nil {
+		return nil, invalidField("cursor", "is malformed")
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, invalidField("cursor", "is malformed")
+	}
+
+	return &appdto.Keyset{StartDate: startDate, ID: id}, nil
+}
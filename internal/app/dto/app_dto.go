@@ -3,22 +3,49 @@ package appdto
 import (
 	"time"
 
+	"github.com/Neroframe/sub_crudl/internal/domain"
 	"github.com/google/uuid"
 )
 
 type CreateInput struct {
-	ServiceName string
-	UserID      uuid.UUID
-	StartDate   time.Time
-	EndDate     *time.Time
-	Price       int32
+	ServiceName   string
+	UserID        uuid.UUID
+	StartDate     time.Time
+	EndDate       *time.Time
+	Price         int32
+	Currency      string               // ISO 4217, defaults to DefaultCurrency
+	BillingPeriod domain.BillingPeriod // defaults to monthly
 }
 
+// ImportResult is the outcome of one imported row: the new ID, or the error
+// that kept it from being created. A valid row in a rejected atomic import
+// has neither.
+type ImportResult struct {
+	ID  uuid.UUID
+	Err error
+}
+
+// IdempotencyRecord is a create request remembered under its Idempotency-Key.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	Response    *domain.Subscription // nil until the request has completed
+	CreatedAt   time.Time
+}
+
+// UpdateInput changes only the non-nil fields.
 type UpdateInput struct {
-	ServiceName *string
-	StartDate   *time.Time
-	EndDate     *time.Time
-	Price       *int32
+	ServiceName   *string
+	StartDate     *time.Time
+	EndDate       *time.Time
+	ClearEndDate  bool // remove end_date; mutually exclusive with EndDate
+	Price         *int32
+	Currency      *string
+	BillingPeriod *domain.BillingPeriod
+
+	// IfVersion, when set, makes the update fail with ErrConflict unless the
+	// stored subscription is still at this version.
+	IfVersion *int32
 }
 
 type AggregationFilter struct {
@@ -26,5 +53,76 @@ type AggregationFilter struct {
 	ServiceName *string
 	StartPeriod time.Time
 	EndPeriod   time.Time
+	GroupBy     []GroupField // empty means a single total
+	Currency    string       // report currency, defaults to DefaultCurrency
+	Normalize   bool         // monthly-equivalent cost instead of actual charges
+}
+
+type GroupField string
+
+const (
+	GroupByServiceName GroupField = "service_name"
+	GroupByUserID      GroupField = "user_id"
+	GroupByMonth       GroupField = "month"
+)
+
+// CostBucket is one row of a grouped aggregation; fields that are not
+// part of the grouping are nil.
+type CostBucket struct {
+	ServiceName   *string
+	UserID        *uuid.UUID
+	Month         *time.Time
+	Total         int64
+	Subscriptions int64
+}
+
+// CurrencyCost is a total in a single currency, before conversion to the
+// report currency.
+type CurrencyCost struct {
+	Currency string
+	Total    int64
+}
+
+// CurrencyBucket is a CostBucket in a single currency, as the repository
+// returns it; the service merges currencies after conversion.
+type CurrencyBucket struct {
+	CostBucket
+	Currency string
 }
 
+type ListFilter struct {
+	UserID      *uuid.UUID
+	ServiceName *string
+	Limit       int32  // 0 means default page size
+	Cursor      string // opaque, taken from a previous ListPage.NextCursor
+}
+
+// ExportFilter selects the subscriptions to export; it matches ListFilter
+// without paging.
+type ExportFilter struct {
+	UserID      *uuid.UUID
+	ServiceName *string
+}
+
+type ListPage struct {
+	Items      []*domain.Subscription
+	NextCursor string // empty on the last page
+}
+
+// Keyset is the position of a row in (start_date DESC, id DESC) order.
+type Keyset struct {
+	StartDate time.Time
+	ID        uuid.UUID
+}
+
+type MonthlyCost struct {
+	Month time.Time // first day of the month
+	Total int64
+}
+
+// IssueAPIKeyInput describes a new API key.
+type IssueAPIKeyInput struct {
+	Name      string
+	Scopes    []domain.APIKeyScope
+	ExpiresAt *time.Time // nil for a key that never expires
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Action is a kind of operation on subscriptions the access policy rules on.
+type Action string
+
+const (
+	ActionRead      Action = "read"      // get, list, history and the trash
+	ActionWrite     Action = "write"     // create, import, update, delete and restore
+	ActionExport    Action = "export"    // CSV export
+	ActionAggregate Action = "aggregate" // cost reports
+	ActionPurge     Action = "purge"     // maintenance jobs
+)
+
+// reach is how far a role's access to an action extends.
+type reach int
+
+const (
+	reachNone reach = iota
+	reachOwn        // the caller's own subscriptions
+	reachAll        // every user's subscriptions
+)
+
+// policy is the reach of each role per action. Actions missing for a role
+// are forbidden to it.
+var policy = map[domain.Role]map[Action]reach{
+	domain.RoleAdmin: {
+		ActionRead:      reachAll,
+		ActionWrite:     reachAll,
+		ActionExport:    reachAll,
+		ActionAggregate: reachAll,
+		ActionPurge:     reachAll,
+	},
+	domain.RoleAnalyst: {
+		ActionExport:    reachAll,
+		ActionAggregate: reachAll,
+	},
+	domain.RoleMember: {
+		ActionRead:      reachOwn,
+		ActionWrite:     reachOwn,
+		ActionExport:    reachOwn,
+		ActionAggregate: reachOwn,
+	},
+}
+
+// PolicyError is the policy refusing an action to a role outright.
+type PolicyError struct {
+	Role   domain.Role
+	Action Action
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("the %s role may not %s subscriptions", e.Role, e.Action)
+}
+
+func (e *PolicyError) Unwrap() error { return ErrForbidden }
+
+// grant is what the policy lets the caller touch for one action: every
+// user's subscriptions, or only those of userID.
+type grant struct {
+	all    bool
+	userID uuid.UUID
+}
+
+// authorize consults the policy for the caller of ctx. Calls without a
+// principal are refused; background jobs run as the system (see AsSystem).
+func authorize(ctx context.Context, action Action) (grant, error) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return grant{}, ErrUnauthenticated
+	}
+	switch policy[p.Role][action] {
+	case reachAll:
+		return grant{all: true, userID: p.UserID}, nil
+	case reachOwn:
+		return grant{userID: p.UserID}, nil
+	}
+	return grant{}, &PolicyError{Role: p.Role, Action: action}
+}
+
+// owns reports whether the grant covers a subscription owned by owner.
+// Callers report others' subscriptions as not found so their existence does
+// not leak.
+func (g grant) owns(owner uuid.UUID) bool {
+	return g.all || g.userID == owner
+}
+
+// userFilter resolves the user filter of a read. Grants over every user
+// keep userID as given; others are limited to the caller's subscriptions and
+// may not name anyone else.
+func (g grant) userFilter(userID *uuid.UUID) (*uuid.UUID, error) {
+	if g.all {
+		return userID, nil
+	}
+	if userID != nil && *userID != g.userID {
+		return nil, ErrForbidden
+	}
+	return &g.userID, nil
+}
+
+// owner picks the user a new subscription belongs to: the caller unless
+// userID names someone else, which needs a grant over every user.
+func (g grant) owner(userID uuid.UUID) (uuid.UUID, error) {
+	switch {
+	case userID == uuid.Nil:
+		return g.userID, nil
+	case userID != g.userID && !g.all:
+		return uuid.Nil, ErrForbidden
+	}
+	return userID, nil
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+func TestAuthorize(t *testing.T) {
+	caller := uuid.New()
+	tests := []struct {
+		role   domain.Role
+		action Action
+		want   reach
+	}{
+		{domain.RoleAdmin, ActionRead, reachAll},
+		{domain.RoleAdmin, ActionWrite, reachAll},
+		{domain.RoleAdmin, ActionExport, reachAll},
+		{domain.RoleAdmin, ActionAggregate, reachAll},
+		{domain.RoleAdmin, ActionPurge, reachAll},
+
+		{domain.RoleAnalyst, ActionRead, reachNone},
+		{domain.RoleAnalyst, ActionWrite, reachNone},
+		{domain.RoleAnalyst, ActionExport, reachAll},
+		{domain.RoleAnalyst, ActionAggregate, reachAll},
+		{domain.RoleAnalyst, ActionPurge, reachNone},
+
+		{domain.RoleMember, ActionRead, reachOwn},
+		{domain.RoleMember, ActionWrite, reachOwn},
+		{domain.RoleMember, ActionExport, reachOwn},
+		{domain.RoleMember, ActionAggregate, reachOwn},
+		{domain.RoleMember, ActionPurge, reachNone},
+
+		{domain.Role("unknown"), ActionRead, reachNone},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.action), func(t *testing.T) {
+			ctx := WithPrincipal(context.Background(), Principal{UserID: caller, Role: tt.role})
+			g, err := authorize(ctx, tt.action)
+
+			if tt.want == reachNone {
+				var pe *PolicyError
+				if !errors.As(err, &pe) || !errors.Is(err, ErrForbidden) {
+					t.Fatalf("err = %v, want a PolicyError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("authorize: %v", err)
+			}
+			if g.all != (tt.want == reachAll) || g.userID != caller {
+				t.Errorf("grant = %+v, want all=%v for %s", g, tt.want == reachAll, caller)
+			}
+		})
+	}
+}
+
+func TestAuthorizeWithoutPrincipal(t *testing.T) {
+	if _, err := authorize(context.Background(), ActionRead); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("err = %v, want ErrUnauthenticated", err)
+	}
+	if _, err := authorize(AsSystem(context.Background()), ActionPurge); err != nil {
+		t.Errorf("system caller: %v", err)
+	}
+}
+
+func TestGrantScopes(t *testing.T) {
+	caller, other := uuid.New(), uuid.New()
+	all := grant{all: true, userID: caller}
+	own := grant{userID: caller}
+
+	t.Run("owns", func(t *testing.T) {
+		if !all.owns(other) || !own.owns(caller) || own.owns(other) {
+			t.Error("owns does not follow the grant")
+		}
+	})
+
+	t.Run("userFilter", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			g       grant
+			in      *uuid.UUID
+			want    *uuid.UUID
+			wantErr error
+		}{
+			{"all, no filter", all, nil, nil, nil},
+			{"all, another user", all, &other, &other, nil},
+			{"own, no filter", own, nil, &caller, nil},
+			{"own, self", own, &caller, &caller, nil},
+			{"own, another user", own, &other, nil, ErrForbidden},
+		}
+		for _, tt := range tests {
+			got, err := tt.g.userFilter(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+				continue
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("%s: filter = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			g       grant
+			in      uuid.UUID
+			want    uuid.UUID
+			wantErr error
+		}{
+			{"all, unset", all, uuid.Nil, caller, nil},
+			{"all, another user", all, other, other, nil},
+			{"own, unset", own, uuid.Nil, caller, nil},
+			{"own, self", own, caller, caller, nil},
+			{"own, another user", own, other, uuid.Nil, ErrForbidden},
+		}
+		for _, tt := range tests {
+			got, err := tt.g.owner(tt.in)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("%s: owner = %s, %v; want %s, %v", tt.name, got, err, tt.want, tt.wantErr)
+			}
+		}
+	})
+}
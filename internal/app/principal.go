@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// ErrUnauthenticated means a call reached the service without a caller. It
+// points at a wiring mistake, since every request is authenticated first.
+var ErrUnauthenticated = errors.New("caller is not authenticated")
+
+// ErrForbidden means the caller asked for another user's subscriptions, or
+// for an action their role does not allow (see PolicyError).
+var ErrForbidden = errors.New("access to another user's subscriptions is forbidden")
+
+// Principal is the authenticated caller of a request: a user, or a service
+// holding an API key. Services have no user ID and act as admins, limited
+// only by the scopes of their key.
+type Principal struct {
+	UserID uuid.UUID
+	Role   domain.Role    // what the policy lets the caller do
+	APIKey *domain.APIKey // set for services
+}
+
+// Allows reports whether the caller holds scope. Users hold every scope but
+// admin, which the admin role grants; the policy still decides what their
+// role may do with subscriptions.
+func (p Principal) Allows(scope domain.APIKeyScope) bool {
+	if p.APIKey != nil {
+		return p.APIKey.Allows(scope)
+	}
+	return scope != domain.ScopeAdmin || p.Role == domain.RoleAdmin
+}
+
+type principalKey struct{}
+
+// WithPrincipal attaches the authenticated caller to ctx. Without one the
+// service refuses every call.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// AsSystem makes the service itself the caller, as an admin acting for no
+// user. It is meant for background jobs, never for requests.
+func AsSystem(ctx context.Context) context.Context {
+	return WithPrincipal(ctx, Principal{Role: domain.RoleAdmin})
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// eventsOwner finds the owner of a subscription from its audit trail, which
+// outlives the subscription itself.
+func eventsOwner(events []*domain.SubscriptionEvent) (uuid.UUID, bool) {
+	for _, e := range events {
+		if e.After != nil {
+			return e.After.UserID, true
+		}
+		if e.Before != nil {
+			return e.Before.UserID, true
+		}
+	}
+	return uuid.Nil, false
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/pkg/logger"
+)
+
+// RunTrashPurger calls PurgeDeleted every interval until ctx is cancelled.
+func RunTrashPurger(ctx context.Context, svc SubscriptionService, retention, interval time.Duration, log *logger.Logger) {
+	log.Info("trash purger started", "retention", retention, "interval", interval)
+	ctx = AsSystem(ctx)
+	runEvery(ctx, interval, func() {
+		if _, err := svc.PurgeDeleted(ctx, retention); err != nil {
+			log.Error("trash purge failed", "err", err)
+		}
+	})
+	log.Info("trash purger stopped")
+}
+
+// RunIdempotencyPurger calls PurgeIdempotencyKeys every interval until ctx is
+// cancelled.
+func RunIdempotencyPurger(ctx context.Context, svc SubscriptionService, ttl, interval time.Duration, log *logger.Logger) {
+	log.Info("idempotency purger started", "ttl", ttl, "interval", interval)
+	ctx = AsSystem(ctx)
+	runEvery(ctx, interval, func() {
+		if _, err := svc.PurgeIdempotencyKeys(ctx, ttl); err != nil {
+			log.Error("idempotency purge failed", "err", err)
+		}
+	})
+	log.Info("idempotency purger stopped")
+}
+
+// runEvery calls fn on every tick of interval until ctx is cancelled.
+func runEvery(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package app
+
+// ExchangeRates converts amounts between ISO 4217 currencies.
+type ExchangeRates interface {
+	Supports(currency string) bool
+	Convert(amount int64, from, to string) (int64, error)
+}
@@ -0,0 +1,16 @@
+package app
+
+import (
+	"context"
+
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// RoleService decides the role an authenticated user acts in.
+type RoleService interface {
+	// Resolve picks the strongest role among those claimed by the user's
+	// token, falling back to the role assigned to them in the tenant of ctx
+	// and then to member. Claims that name no known role are ignored.
+	Resolve(ctx context.Context, userID uuid.UUID, claimed []string) (domain.Role, error)
+}
@@ -0,0 +1,14 @@
+package app
+
+import (
+	"context"
+
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// UserRoleRepository holds the roles assigned to users within the tenant of
+// the context, for tokens that do not carry one.
+type UserRoleRepository interface {
+	GetUserRole(ctx context.Context, userID uuid.UUID) (domain.Role, error) // ErrRoleNotAssigned if none
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/Neroframe/sub_crudl/pkg/logger"
+	"github.com/google/uuid"
+)
+
+type roleService struct {
+	repo UserRoleRepository
+	log  *logger.Logger
+}
+
+func NewRoleService(repo UserRoleRepository, logger *logger.Logger) RoleService {
+	return &roleService{repo: repo, log: logger}
+}
+
+var ErrRoleNotAssigned = errors.New("no role assigned to user")
+
+// roleRank orders roles from weakest to strongest.
+var roleRank = map[domain.Role]int{
+	domain.RoleMember:  1,
+	domain.RoleAnalyst: 2,
+	domain.RoleAdmin:   3,
+}
+
+func (s *roleService) Resolve(ctx context.Context, userID uuid.UUID, claimed []string) (domain.Role, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "ResolveRole", "user_id", userID)
+
+	var role domain.Role
+	for _, c := range claimed {
+		if r := domain.Role(c); r.Valid() && roleRank[r] > roleRank[role] {
+			role = r
+		}
+	}
+	if role != "" {
+		return role, nil
+	}
+
+	role, err := s.repo.GetUserRole(ctx, userID)
+	if errors.Is(err, ErrRoleNotAssigned) {
+		return domain.RoleMember, nil
+	}
+	if err != nil {
+		log.Error("repo.GetUserRole failed", "error", err)
+		return "", fmt.Errorf("failed to get user role: %w", err)
+	}
+	log.Debug("role assigned to user", "role", role)
+	return role, nil
+}
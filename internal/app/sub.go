@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"time"
 
 	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
 	"github.com/Neroframe/sub_crudl/internal/domain"
@@ -11,9 +12,19 @@ import (
 // SubscriptionService is the application boundary interface.
 type SubscriptionService interface {
 	Create(ctx context.Context, input appdto.CreateInput) (*domain.Subscription, error)
+	CreateIdempotent(ctx context.Context, key string, input appdto.CreateInput) (sub *domain.Subscription, replayed bool, err error)
+	Import(ctx context.Context, inputs []appdto.CreateInput, atomic bool) ([]appdto.ImportResult, error)
 	Get(ctx context.Context, id uuid.UUID) (*domain.Subscription, error)
-	List(ctx context.Context, userID *uuid.UUID, serviceName *string) ([]*domain.Subscription, error)
+	List(ctx context.Context, filter appdto.ListFilter) (*appdto.ListPage, error)
+	Export(ctx context.Context, filter appdto.ExportFilter, fn func(*domain.Subscription) error) error
 	Update(ctx context.Context, id uuid.UUID, input appdto.UpdateInput) (*domain.Subscription, error)
 	Delete(ctx context.Context, id uuid.UUID) error
-	Aggregate(ctx context.Context, filter appdto.AggregationFilter) (int32, error)
+	Restore(ctx context.Context, id uuid.UUID) (*domain.Subscription, error)
+	History(ctx context.Context, id uuid.UUID) ([]*domain.SubscriptionEvent, error)
+	ListDeleted(ctx context.Context, userID *uuid.UUID, limit int32) ([]*domain.Subscription, error)
+	PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error)
+	PurgeIdempotencyKeys(ctx context.Context, ttl time.Duration) (int64, error)
+	Aggregate(ctx context.Context, filter appdto.AggregationFilter) (int64, error)
+	AggregateGrouped(ctx context.Context, filter appdto.AggregationFilter) ([]*appdto.CostBucket, error)
+	TimeSeries(ctx context.Context, filter appdto.AggregationFilter) ([]*appdto.MonthlyCost, error)
 }
@@ -2,16 +2,55 @@ package app
 
 import (
 	"context"
+	"time"
 
-	queries "github.com/Neroframe/sub_crudl/internal/infra/postgres/queries/generated"
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
 	"github.com/google/uuid"
 )
 
+// SubscriptionRepository is the storage port. It is expressed in domain
+// types so backends keep their own row mapping.
+//
+// Every method is scoped to the tenant of its context (see WithTenant) and
+// fails with ErrNoTenant without one, except the Purge methods: they are
+// maintenance jobs and work across all tenants.
 type SubscriptionRepository interface {
-	Create(ctx context.Context, arg queries.CreateSubscriptionParams) error
-	GetByID(ctx context.Context, id uuid.UUID) (queries.Subscription, error)
-	List(ctx context.Context, userID *uuid.UUID, serviceName *string) ([]queries.Subscription, error)
-	Update(ctx context.Context, arg queries.UpdateSubscriptionParams) error
+	// WithTx runs fn against a repository bound to a single transaction,
+	// committing if fn returns nil and rolling back otherwise.
+	WithTx(ctx context.Context, fn func(repo SubscriptionRepository) error) error
+
+	// Create stores a new subscription at version 1.
+	Create(ctx context.Context, sub *domain.Subscription) error
+	// GetByID returns ErrNotFound unless the subscription exists and is not
+	// in the trash; Update, Delete and Restore report missing rows the same way.
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error)
+	List(ctx context.Context, userID *uuid.UUID, serviceName *string, after *appdto.Keyset, limit int32) ([]*domain.Subscription, error)
+	// Update overwrites the editable fields of sub and bumps its version. It
+	// returns ErrConflict when the stored row is no longer at version.
+	Update(ctx context.Context, sub *domain.Subscription, version int32) error
 	Delete(ctx context.Context, id uuid.UUID) error
-	AggregateCost(ctx context.Context, arg queries.AggregateCostParams) (interface{}, error)
+	// Restore takes a subscription out of the trash; ErrNotFound means it
+	// is not there.
+	Restore(ctx context.Context, id uuid.UUID) error
+	ListDeleted(ctx context.Context, userID *uuid.UUID, limit int32) ([]*domain.Subscription, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+
+	// AggregateCost sums charges per currency; filter.GroupBy and
+	// filter.Currency are ignored.
+	AggregateCost(ctx context.Context, filter appdto.AggregationFilter) ([]appdto.CurrencyCost, error)
+	// AggregateCostGrouped returns one row per bucket and currency, ordered
+	// by bucket and then currency. filter.Currency is ignored.
+	AggregateCostGrouped(ctx context.Context, filter appdto.AggregationFilter) ([]appdto.CurrencyBucket, error)
+
+	CreateEvent(ctx context.Context, event *domain.SubscriptionEvent) error
+	ListEvents(ctx context.Context, subscriptionID uuid.UUID) ([]*domain.SubscriptionEvent, error)
+
+	// ClaimIdempotencyKey records key for the request with the given hash. It
+	// reports false, without error, when the key is already taken.
+	ClaimIdempotencyKey(ctx context.Context, key, requestHash string) (bool, error)
+	GetIdempotencyKey(ctx context.Context, key string) (*appdto.IdempotencyRecord, error) // ErrNotFound if unknown
+	// SaveIdempotentResponse stores the result to replay for a claimed key.
+	SaveIdempotentResponse(ctx context.Context, key string, sub *domain.Subscription) error
+	PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error)
 }
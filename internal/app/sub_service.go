@@ -2,84 +2,347 @@ package app
 
 import (
 	"context"
-	"database/sql"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
 	"github.com/Neroframe/sub_crudl/internal/domain"
-	queries "github.com/Neroframe/sub_crudl/internal/infra/postgres/queries/generated"
 	"github.com/Neroframe/sub_crudl/pkg/logger"
 	"github.com/google/uuid"
 )
 
 type service struct {
-	repo SubscriptionRepository
-	log  *logger.Logger
+	repo  SubscriptionRepository
+	rates ExchangeRates
+	log   *logger.Logger
 }
 
-func NewSubscriptionService(repo SubscriptionRepository, logger *logger.Logger) SubscriptionService {
-	return &service{repo: repo, log: logger}
+func NewSubscriptionService(repo SubscriptionRepository, rates ExchangeRates, logger *logger.Logger) SubscriptionService {
+	return &service{repo: repo, rates: rates, log: logger}
 }
 
+const (
+	DefaultPageSize int32 = 50
+	MaxPageSize     int32 = 500
+
+	// ExportBatchSize is how many rows Export reads from the repository at a time.
+	ExportBatchSize int32 = 500
+
+	// MaxImportRows caps a single bulk import.
+	MaxImportRows = 10000
+
+	// MaxAggregateMonths caps the window of cost reports. Their queries
+	// expand the window into one row per month, and a time series has one
+	// point per month whether or not anything was spent.
+	MaxAggregateMonths = 120
+
+	// MaxIdempotencyKeyLength bounds the Idempotency-Key a client may send.
+	MaxIdempotencyKeyLength = 255
+
+	// DefaultCurrency matches the column default in the subscriptions table.
+	DefaultCurrency = "RUB"
+)
+
 var (
 	ErrNotFound     = errors.New("subscription not found")
 	ErrInvalidInput = errors.New("invalid input")
+	ErrConflict     = errors.New("subscription was modified concurrently")
+
+	// ErrIdempotencyKeyReused means the key was first used for a different request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
 )
 
+// FieldError is an ErrInvalidInput caused by a single input field.
+type FieldError struct {
+	Field  string // name of the field in requests, e.g. "price"
+	Reason string // e.g. "must not be negative"
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%v: %s %s", ErrInvalidInput, e.Field, e.Reason)
+}
+
+func (e *FieldError) Unwrap() error { return ErrInvalidInput }
+
+func invalidField(field, reason string) error {
+	return &FieldError{Field: field, Reason: reason}
+}
+
 func (s *service) Create(ctx context.Context, input appdto.CreateInput) (*domain.Subscription, error) {
-	log := s.log.With("service", "Create")
+	log := logger.FromContext(ctx, s.log).With("service", "Create")
+	log.Debug("creating subscription", "input", input)
+
+	g, err := authorize(ctx, ActionWrite)
+	if err != nil {
+		log.Info("create rejected by policy", "error", err)
+		return nil, err
+	}
+	created, err := s.prepareCreate(ctx, log, g, input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithTx(ctx, func(repo SubscriptionRepository) error {
+		return s.insert(ctx, repo, created)
+	})
+	if err != nil {
+		log.Error("repo.Create failed", "err", err)
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	log.Info("service.Create success", "id", created.ID)
+	return created, nil
+}
+
+// CreateIdempotent is Create guarded by a client-supplied key. The first
+// request with a key creates the subscription and stores it under the key;
+// repeating the same request returns that subscription with replayed set,
+// while reusing the key for a different request fails with
+// ErrIdempotencyKeyReused. Invalid input is rejected before the key is
+// claimed, so a corrected request may reuse it.
+func (s *service) CreateIdempotent(ctx context.Context, key string, input appdto.CreateInput) (*domain.Subscription, bool, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "CreateIdempotent", "key", key)
 	log.Debug("creating subscription", "input", input)
 
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		log.Error("invalid idempotency key", "length", len(key))
+		return nil, false, invalidField("Idempotency-Key", fmt.Sprintf("must be 1 to %d characters", MaxIdempotencyKeyLength))
+	}
+
+	g, err := authorize(ctx, ActionWrite)
+	if err != nil {
+		log.Info("create rejected by policy", "error", err)
+		return nil, false, err
+	}
+	created, err := s.prepareCreate(ctx, log, g, input)
+	if err != nil {
+		return nil, false, err
+	}
+	// Hashed after defaults and case are applied, so equivalent bodies match;
+	// the owner is included, as the same body from another caller is another
+	// request.
+	hash, err := requestHash(created)
+	if err != nil {
+		log.Error("failed to hash request", "error", err)
+		return nil, false, err
+	}
+
+	// Claiming the key and creating the subscription commit together, so a
+	// key is never left pointing at a request that did not happen.
+	var result *domain.Subscription
+	replayed := false
+	err = s.repo.WithTx(ctx, func(repo SubscriptionRepository) error {
+		claimed, err := repo.ClaimIdempotencyKey(ctx, key, hash)
+		if err != nil {
+			log.Error("repo.ClaimIdempotencyKey failed", "error", err)
+			return fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+
+		if !claimed {
+			rec, err := repo.GetIdempotencyKey(ctx, key)
+			if err != nil {
+				log.Error("repo.GetIdempotencyKey failed", "error", err)
+				return fmt.Errorf("failed to get idempotency key: %w", err)
+			}
+			if rec.RequestHash != hash {
+				log.Info("idempotency key reused for a different request")
+				return ErrIdempotencyKeyReused
+			}
+			if rec.Response == nil {
+				log.Error("idempotency key has no stored response")
+				return fmt.Errorf("idempotency key %q has no stored response", key)
+			}
+			result, replayed = rec.Response, true
+			return nil
+		}
+
+		if err := s.insert(ctx, repo, created); err != nil {
+			log.Error("repo.Create failed", "error", err)
+			return fmt.Errorf("failed to create subscription: %w", err)
+		}
+		if err := repo.SaveIdempotentResponse(ctx, key, created); err != nil {
+			log.Error("repo.SaveIdempotentResponse failed", "error", err)
+			return fmt.Errorf("failed to save idempotent response: %w", err)
+		}
+		result = created
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if replayed {
+		log.Info("idempotent create replayed", "id", result.ID)
+	} else {
+		log.Info("service.CreateIdempotent success", "id", result.ID)
+	}
+	return result, replayed, nil
+}
+
+// requestHash fingerprints a prepared subscription for idempotency checks.
+// The generated ID is left out, as it differs on every attempt.
+func requestHash(sub *domain.Subscription) (string, error) {
+	fields := *sub
+	fields.ID = uuid.Nil
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Import creates subscriptions in bulk. Results line up with inputs by index.
+// In atomic mode nothing is written unless every row is valid, and a storage
+// error aborts the whole batch; otherwise each row is committed on its own.
+func (s *service) Import(ctx context.Context, inputs []appdto.CreateInput, atomic bool) ([]appdto.ImportResult, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "Import")
+	log.Debug("importing subscriptions", "rows", len(inputs), "atomic", atomic)
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: no rows to import", ErrInvalidInput)
+	}
+	if len(inputs) > MaxImportRows {
+		log.Error("too many rows", "rows", len(inputs))
+		return nil, fmt.Errorf("%w: at most %d rows per import", ErrInvalidInput, MaxImportRows)
+	}
+	g, err := authorize(ctx, ActionWrite)
+	if err != nil {
+		log.Info("import rejected by policy", "error", err)
+		return nil, err
+	}
+
+	results := make([]appdto.ImportResult, len(inputs))
+	subs := make([]*domain.Subscription, len(inputs))
+	invalid := 0
+	for i, input := range inputs {
+		subs[i], results[i].Err = s.prepareCreate(ctx, log, g, input)
+		if results[i].Err != nil {
+			invalid++
+		}
+	}
+
+	if atomic {
+		if invalid > 0 {
+			log.Info("import rejected", "invalid", invalid)
+			return results, nil
+		}
+		err := s.repo.WithTx(ctx, func(repo SubscriptionRepository) error {
+			for i, sub := range subs {
+				if err := s.insert(ctx, repo, sub); err != nil {
+					return fmt.Errorf("row %d: %w", i+1, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Error("atomic import failed", "error", err)
+			return nil, fmt.Errorf("failed to import subscriptions: %w", err)
+		}
+		for i := range results {
+			results[i].ID = subs[i].ID
+		}
+		log.Info("service.Import success", "created", len(subs))
+		return results, nil
+	}
+
+	created := 0
+	for i, sub := range subs {
+		if results[i].Err != nil {
+			continue
+		}
+		err := s.repo.WithTx(ctx, func(repo SubscriptionRepository) error {
+			return s.insert(ctx, repo, sub)
+		})
+		if err != nil {
+			log.Error("row import failed", "row", i+1, "error", err)
+			results[i].Err = fmt.Errorf("failed to create subscription: %w", err)
+			continue
+		}
+		results[i].ID = sub.ID
+		created++
+	}
+
+	log.Info("service.Import done", "created", created, "failed", len(inputs)-created)
+	return results, nil
+}
+
+// prepareCreate validates input and builds the subscription to store. The
+// owner defaults to the caller.
+func (s *service) prepareCreate(ctx context.Context, log *slog.Logger, g grant, input appdto.CreateInput) (*domain.Subscription, error) {
+	owner, err := g.owner(input.UserID)
+	if err != nil {
+		log.Info("subscription for another user rejected", "user_id", input.UserID)
+		return nil, err
+	}
+
 	// Input validation
+	if owner == uuid.Nil {
+		log.Error("user_id is required")
+		return nil, invalidField("user_id", "is required")
+	}
 	if input.ServiceName == "" {
 		log.Error("service_name is required")
-		return nil, fmt.Errorf("%w: service_name", ErrInvalidInput)
+		return nil, invalidField("service_name", "is required")
 	}
 	if input.Price < 0 {
 		log.Error("price must be non-negative", "price", input.Price)
-		return nil, fmt.Errorf("%w: price", ErrInvalidInput)
+		return nil, invalidField("price", "must not be negative")
 	}
 	if input.EndDate != nil && input.StartDate.After(*input.EndDate) {
 		log.Error("start_date cannot be after end_date", "start", input.StartDate, "end", *input.EndDate)
-		return nil, fmt.Errorf("%w: date range", ErrInvalidInput)
+		return nil, invalidField("end_date", "must not be before start_date")
 	}
-
-	sub := queries.CreateSubscriptionParams{
-		ID:          uuid.New(),
-		ServiceName: input.ServiceName,
-		UserID:      input.UserID,
-		StartDate:   input.StartDate,
-		EndDate:     sql.NullTime{Time: *input.EndDate, Valid: input.EndDate != nil},
-		Price:       input.Price,
+	currency, err := s.normalizeCurrency(input.Currency)
+	if err != nil {
+		log.Error("unsupported currency", "currency", input.Currency)
+		return nil, err
 	}
-
-	if err := s.repo.Create(ctx, sub); err != nil {
-		s.log.Error("repo.Create failed", "err", err)
-		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	period := input.BillingPeriod
+	if period == "" {
+		period = domain.BillingMonthly
 	}
-
-	var endDate *time.Time
-	if sub.EndDate.Valid {
-		endDate = &sub.EndDate.Time
+	if !period.Valid() {
+		log.Error("invalid billing_period", "billing_period", period)
+		return nil, invalidField("billing_period", "must be weekly, monthly, quarterly or yearly")
 	}
 
-	s.log.Info("service.Create success", "id", sub.ID)
 	return &domain.Subscription{
-		ID:          sub.ID,
-		ServiceName: sub.ServiceName,
-		UserID:      sub.UserID,
-		StartDate:   sub.StartDate,
-		EndDate:     endDate,
-		Price:       sub.Price,
+		ID:            uuid.New(),
+		ServiceName:   input.ServiceName,
+		UserID:        owner,
+		StartDate:     input.StartDate,
+		EndDate:       input.EndDate,
+		Price:         input.Price,
+		Currency:      currency,
+		BillingPeriod: period,
+		Version:       1,
 	}, nil
 }
 
+// insert writes a new subscription together with its create event.
+func (s *service) insert(ctx context.Context, repo SubscriptionRepository, sub *domain.Subscription) error {
+	if err := repo.Create(ctx, sub); err != nil {
+		return err
+	}
+	return s.recordEvent(ctx, repo, sub.ID, domain.EventCreated, nil, sub)
+}
+
 func (s *service) Get(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
-	log := s.log.With("service", "Get", "id", id)
+	log := logger.FromContext(ctx, s.log).With("service", "Get", "id", id)
 	log.Debug("fetching subscription")
 
+	g, err := authorize(ctx, ActionRead)
+	if err != nil {
+		log.Info("get rejected by policy", "error", err)
+		return nil, err
+	}
 	sub, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
@@ -89,28 +352,109 @@ func (s *service) Get(ctx context.Context, id uuid.UUID) (*domain.Subscription,
 		log.Error("repo.GetByID failed", "error", err)
 		return nil, fmt.Errorf("failed to get subscription: %w", err)
 	}
+	if !g.owns(sub.UserID) {
+		log.Info("subscription belongs to another user")
+		return nil, ErrNotFound
+	}
 
-	return mapToDomain(sub), nil
+	return sub, nil
 }
 
-func (s *service) List(ctx context.Context, userID *uuid.UUID, serviceName *string) ([]*domain.Subscription, error) {
-	log := s.log.With("service", "List", "user_id", userID, "service_name", serviceName)
+func (s *service) List(ctx context.Context, filter appdto.ListFilter) (*appdto.ListPage, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "List", "user_id", filter.UserID, "service_name", filter.ServiceName, "limit", filter.Limit)
 	log.Debug("listing subscriptions")
 
-	subs, err := s.repo.List(ctx, userID, serviceName)
+	limit := filter.Limit
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	if limit < 0 || limit > MaxPageSize {
+		log.Error("limit out of range", "limit", filter.Limit)
+		return nil, invalidField("limit", fmt.Sprintf("must be between 1 and %d", MaxPageSize))
+	}
+
+	g, err := authorize(ctx, ActionRead)
+	if err != nil {
+		log.Info("list rejected by policy", "error", err)
+		return nil, err
+	}
+	userID, err := g.userFilter(filter.UserID)
+	if err != nil {
+		log.Info("list of another user's subscriptions rejected")
+		return nil, err
+	}
+
+	var after *appdto.Keyset
+	if filter.Cursor != "" {
+		k, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			log.Error("invalid cursor", "cursor", filter.Cursor)
+			return nil, err
+		}
+		after = k
+	}
+
+	// Fetch one extra row to find out whether another page exists
+	subs, err := s.repo.List(ctx, userID, filter.ServiceName, after, limit+1)
 	if err != nil {
 		log.Error("repo.List failed", "error", err)
 		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
 	}
 
-	var result []*domain.Subscription
-	for _, sub := range subs {
-		result = append(result, mapToDomain(sub))
+	page := &appdto.ListPage{Items: []*domain.Subscription{}}
+	if len(subs) > int(limit) {
+		subs = subs[:limit]
+		last := subs[len(subs)-1]
+		page.NextCursor = encodeCursor(appdto.Keyset{StartDate: last.StartDate, ID: last.ID})
+	}
+	page.Items = append(page.Items, subs...)
+
+	log.Info("subscriptions listed", "count", len(page.Items), "has_more", page.NextCursor != "")
+	return page, nil
+}
+
+// Export calls fn for every subscription matching the filter, in List order.
+// Rows are read in keyset batches so memory use does not grow with the result;
+// an error from fn stops the export and is returned as is.
+func (s *service) Export(ctx context.Context, filter appdto.ExportFilter, fn func(*domain.Subscription) error) error {
+	log := logger.FromContext(ctx, s.log).With("service", "Export", "user_id", filter.UserID, "service_name", filter.ServiceName)
+	log.Debug("exporting subscriptions")
+
+	g, err := authorize(ctx, ActionExport)
+	if err != nil {
+		log.Info("export rejected by policy", "error", err)
+		return err
+	}
+	userID, err := g.userFilter(filter.UserID)
+	if err != nil {
+		log.Info("export of another user's subscriptions rejected")
+		return err
 	}
 
-	log.Info("subscriptions listed", "count", len(result))
-	return result, nil
+	var after *appdto.Keyset
+	count := 0
+	for {
+		subs, err := s.repo.List(ctx, userID, filter.ServiceName, after, ExportBatchSize)
+		if err != nil {
+			log.Error("repo.List failed", "error", err, "exported", count)
+			return fmt.Errorf("failed to export subscriptions: %w", err)
+		}
+		for _, sub := range subs {
+			if err := fn(sub); err != nil {
+				return err
+			}
+		}
+		count += len(subs)
+
+		if len(subs) < int(ExportBatchSize) {
+			break
+		}
+		last := subs[len(subs)-1]
+		after = &appdto.Keyset{StartDate: last.StartDate, ID: last.ID}
+	}
 
+	log.Info("subscriptions exported", "count", count)
+	return nil
 }
 
 func (s *service) Update(
@@ -118,153 +462,545 @@ func (s *service) Update(
 	id uuid.UUID,
 	input appdto.UpdateInput,
 ) (*domain.Subscription, error) {
-	log := s.log.With("service", "Update", "id", id)
+	log := logger.FromContext(ctx, s.log).With("service", "Update", "id", id)
 	log.Debug("updating subscription", "input", input)
 
-	// 1) Fetch existing record from repo (SQLC type)
-	qsub, err := s.repo.GetByID(ctx, id)
+	g, err := authorize(ctx, ActionWrite)
 	if err != nil {
-		log.Error("repo.GetByID failed", "error", err)
-		return nil, fmt.Errorf("failed to fetch subscription: %w", err)
+		log.Info("update rejected by policy", "error", err)
+		return nil, err
 	}
 
-	// 2) Map SQLC type → domain model
-	var dom = &domain.Subscription{
-		ID:          qsub.ID,
-		ServiceName: qsub.ServiceName,
-		Price:       qsub.Price,
-		UserID:      qsub.UserID,
-		StartDate:   qsub.StartDate,
-	}
-	if qsub.EndDate.Valid {
-		dom.EndDate = &qsub.EndDate.Time
+	// Read-modify-write and the audit entry share one transaction
+	var dom *domain.Subscription
+	err = s.repo.WithTx(ctx, func(repo SubscriptionRepository) error {
+		// 1) Fetch existing record
+		before, err := repo.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				log.Info("subscription not found")
+				return ErrNotFound
+			}
+			log.Error("repo.GetByID failed", "error", err)
+			return fmt.Errorf("failed to fetch subscription: %w", err)
+		}
+		if !g.owns(before.UserID) {
+			log.Info("subscription belongs to another user")
+			return ErrNotFound
+		}
+
+		if input.IfVersion != nil && *input.IfVersion != before.Version {
+			log.Info("version mismatch", "expected", *input.IfVersion, "actual", before.Version)
+			return ErrConflict
+		}
+
+		// 2) Apply updates + validate on a copy, keeping before for the audit trail
+		updated := *before
+		dom = &updated
+		if err := s.applyUpdate(dom, input); err != nil {
+			log.Error("invalid update", "error", err)
+			return err
+		}
+
+		// 3) Call repo.Update; it only matches the version read above
+		if err := repo.Update(ctx, dom, before.Version); err != nil {
+			if errors.Is(err, ErrConflict) {
+				log.Info("concurrent update detected", "version", before.Version)
+				return ErrConflict
+			}
+			if errors.Is(err, ErrNotFound) {
+				log.Info("subscription deleted concurrently")
+				return ErrNotFound
+			}
+			log.Error("repo.Update failed", "error", err)
+			return fmt.Errorf("failed to update subscription: %w", err)
+		}
+		dom.Version = before.Version + 1
+
+		return s.recordEvent(ctx, repo, id, domain.EventUpdated, before, dom)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 3) Apply updates + validate
+	log.Info("subscription updated", "id", id)
+	return dom, nil
+}
+
+// applyUpdate validates input and applies it to sub in place.
+func (s *service) applyUpdate(sub *domain.Subscription, input appdto.UpdateInput) error {
 	if input.ServiceName != nil {
 		if *input.ServiceName == "" {
-			log.Error("service_name cannot be empty")
-			return nil, fmt.Errorf("%w: service_name", ErrInvalidInput)
+			return invalidField("service_name", "must not be empty")
 		}
-		dom.ServiceName = *input.ServiceName
+		sub.ServiceName = *input.ServiceName
 	}
 	if input.Price != nil {
 		if *input.Price < 0 {
-			log.Error("price must be non-negative", "price", *input.Price)
-			return nil, fmt.Errorf("%w: price", ErrInvalidInput)
+			return invalidField("price", "must not be negative")
 		}
-		dom.Price = *input.Price
+		sub.Price = *input.Price
+	}
+	if input.Currency != nil {
+		currency, err := s.normalizeCurrency(*input.Currency)
+		if err != nil {
+			return err
+		}
+		sub.Currency = currency
+	}
+	if input.BillingPeriod != nil {
+		if !input.BillingPeriod.Valid() {
+			return invalidField("billing_period", "must be weekly, monthly, quarterly or yearly")
+		}
+		sub.BillingPeriod = *input.BillingPeriod
 	}
 	if input.StartDate != nil {
-		dom.StartDate = *input.StartDate
+		sub.StartDate = *input.StartDate
+	}
+	if input.EndDate != nil && input.ClearEndDate {
+		return invalidField("end_date", "cannot be both set and cleared")
 	}
 	if input.EndDate != nil {
-		dom.EndDate = input.EndDate
-	}
-	if dom.EndDate != nil && dom.StartDate.After(*dom.EndDate) {
-		log.Error("start_date cannot be after end_date",
-			"start", dom.StartDate, "end", *dom.EndDate)
-		return nil, fmt.Errorf("%w: date range", ErrInvalidInput)
-	}
-
-	// 4) Map domain → SQLC params
-	params := queries.UpdateSubscriptionParams{
-		ID:          dom.ID,
-		ServiceName: dom.ServiceName,
-		Price:       dom.Price,
-		StartDate:   dom.StartDate,
-		EndDate: sql.NullTime{Time: func() time.Time {
-			if dom.EndDate != nil {
-				return *dom.EndDate
-			}
-			return time.Time{}
-		}(), Valid: dom.EndDate != nil},
+		sub.EndDate = input.EndDate
 	}
-
-	// 5) Call repo.Update
-	if err := s.repo.Update(ctx, params); err != nil {
-		log.Error("repo.Update failed", "error", err)
-		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	if input.ClearEndDate {
+		sub.EndDate = nil
 	}
-
-	log.Info("subscription updated", "id", id)
-	return dom, nil
+	if sub.EndDate != nil && sub.StartDate.After(*sub.EndDate) {
+		return invalidField("end_date", "must not be before start_date")
+	}
+	return nil
 }
 
 func (s *service) Delete(ctx context.Context, id uuid.UUID) error {
-	log := s.log.With("service", "Delete", "id", id)
+	log := logger.FromContext(ctx, s.log).With("service", "Delete", "id", id)
 	log.Debug("deleting subscription")
 
-	if err := s.repo.Delete(ctx, id); err != nil {
-		log.Error("repo.Delete failed", "error", err)
-		return fmt.Errorf("failed to delete subscription: %w", err)
+	g, err := authorize(ctx, ActionWrite)
+	if err != nil {
+		log.Info("delete rejected by policy", "error", err)
+		return err
+	}
+
+	err = s.repo.WithTx(ctx, func(repo SubscriptionRepository) error {
+		sub, err := repo.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				log.Info("subscription not found")
+				return ErrNotFound
+			}
+			log.Error("repo.GetByID failed", "error", err)
+			return fmt.Errorf("failed to fetch subscription: %w", err)
+		}
+		if !g.owns(sub.UserID) {
+			log.Info("subscription belongs to another user")
+			return ErrNotFound
+		}
+		if err := repo.Delete(ctx, id); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				log.Info("subscription deleted concurrently")
+				return ErrNotFound
+			}
+			log.Error("repo.Delete failed", "error", err)
+			return fmt.Errorf("failed to delete subscription: %w", err)
+		}
+		return s.recordEvent(ctx, repo, id, domain.EventDeleted, sub, nil)
+	})
+	if err != nil {
+		return err
 	}
 
 	log.Info("subscription deleted", "id", id)
 	return nil
 }
 
+func (s *service) Restore(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "Restore", "id", id)
+	log.Debug("restoring subscription")
+
+	g, err := authorize(ctx, ActionWrite)
+	if err != nil {
+		log.Info("restore rejected by policy", "error", err)
+		return nil, err
+	}
+
+	var restored *domain.Subscription
+	err = s.repo.WithTx(ctx, func(repo SubscriptionRepository) error {
+		if err := repo.Restore(ctx, id); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				log.Info("subscription not in trash")
+				return ErrNotFound
+			}
+			log.Error("repo.Restore failed", "error", err)
+			return fmt.Errorf("failed to restore subscription: %w", err)
+		}
+
+		sub, err := repo.GetByID(ctx, id)
+		if err != nil {
+			log.Error("repo.GetByID failed", "error", err)
+			return fmt.Errorf("failed to get subscription: %w", err)
+		}
+		// Checked after the fact because trashed rows are not readable;
+		// returning an error rolls the restore back
+		if !g.owns(sub.UserID) {
+			log.Info("subscription belongs to another user")
+			return ErrNotFound
+		}
+		restored = sub
+
+		return s.recordEvent(ctx, repo, id, domain.EventRestored, nil, restored)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("subscription restored", "id", id)
+	return restored, nil
+}
+
+// History returns the audit trail of a subscription, oldest first.
+func (s *service) History(ctx context.Context, id uuid.UUID) ([]*domain.SubscriptionEvent, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "History", "id", id)
+	log.Debug("fetching subscription history")
+
+	g, err := authorize(ctx, ActionRead)
+	if err != nil {
+		log.Info("history rejected by policy", "error", err)
+		return nil, err
+	}
+	events, err := s.repo.ListEvents(ctx, id)
+	if err != nil {
+		log.Error("repo.ListEvents failed", "error", err)
+		return nil, fmt.Errorf("failed to fetch subscription history: %w", err)
+	}
+	if owner, ok := eventsOwner(events); ok && !g.owns(owner) {
+		log.Info("subscription belongs to another user")
+		return nil, ErrNotFound
+	}
+	if events == nil {
+		events = []*domain.SubscriptionEvent{}
+	}
+
+	log.Info("subscription history fetched", "count", len(events))
+	return events, nil
+}
+
+func (s *service) ListDeleted(ctx context.Context, userID *uuid.UUID, limit int32) ([]*domain.Subscription, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "ListDeleted", "user_id", userID, "limit", limit)
+	log.Debug("listing trashed subscriptions")
+
+	g, err := authorize(ctx, ActionRead)
+	if err != nil {
+		log.Info("trash listing rejected by policy", "error", err)
+		return nil, err
+	}
+	userID, err = g.userFilter(userID)
+	if err != nil {
+		log.Info("trash of another user rejected")
+		return nil, err
+	}
+
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	if limit < 0 || limit > MaxPageSize {
+		log.Error("limit out of range", "limit", limit)
+		return nil, invalidField("limit", fmt.Sprintf("must be between 1 and %d", MaxPageSize))
+	}
+
+	subs, err := s.repo.ListDeleted(ctx, userID, limit)
+	if err != nil {
+		log.Error("repo.ListDeleted failed", "error", err)
+		return nil, fmt.Errorf("failed to list deleted subscriptions: %w", err)
+	}
+
+	if subs == nil {
+		subs = []*domain.Subscription{}
+	}
+
+	log.Info("trashed subscriptions listed", "count", len(subs))
+	return subs, nil
+}
+
+// PurgeDeleted permanently removes subscriptions that have been in the
+// trash for longer than retention.
+func (s *service) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "PurgeDeleted", "retention", retention)
+	log.Debug("purging trashed subscriptions")
+
+	if _, err := authorize(ctx, ActionPurge); err != nil {
+		log.Info("purge rejected by policy", "error", err)
+		return 0, err
+	}
+
+	if retention <= 0 {
+		log.Error("retention must be positive")
+		return 0, invalidField("retention", "must be positive")
+	}
+
+	n, err := s.repo.PurgeDeleted(ctx, time.Now().Add(-retention))
+	if err != nil {
+		log.Error("repo.PurgeDeleted failed", "error", err)
+		return 0, fmt.Errorf("failed to purge deleted subscriptions: %w", err)
+	}
+
+	log.Info("trashed subscriptions purged", "count", n)
+	return n, nil
+}
+
+// PurgeIdempotencyKeys forgets idempotency keys older than ttl; a request
+// repeated after that is treated as new.
+func (s *service) PurgeIdempotencyKeys(ctx context.Context, ttl time.Duration) (int64, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "PurgeIdempotencyKeys", "ttl", ttl)
+	log.Debug("purging idempotency keys")
+
+	if _, err := authorize(ctx, ActionPurge); err != nil {
+		log.Info("purge rejected by policy", "error", err)
+		return 0, err
+	}
+
+	if ttl <= 0 {
+		log.Error("ttl must be positive")
+		return 0, invalidField("ttl", "must be positive")
+	}
+
+	n, err := s.repo.PurgeIdempotencyKeys(ctx, time.Now().Add(-ttl))
+	if err != nil {
+		log.Error("repo.PurgeIdempotencyKeys failed", "error", err)
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+
+	log.Info("idempotency keys purged", "count", n)
+	return n, nil
+}
+
 func (s *service) Aggregate(
 	ctx context.Context,
 	filter appdto.AggregationFilter,
-) (int32, error) {
-	log := s.log.With("service", "Aggregate", "filter", filter)
+) (int64, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "Aggregate", "filter", filter)
 	log.Debug("aggregating subscriptions")
 
-	// Validate date range
-	if filter.StartPeriod.After(filter.EndPeriod) {
-		log.Error("start_period cannot be after end_period",
-			"start", filter.StartPeriod, "end", filter.EndPeriod)
-		return 0, fmt.Errorf("%w: date range", ErrInvalidInput)
+	g, err := authorize(ctx, ActionAggregate)
+	if err != nil {
+		log.Info("aggregate rejected by policy", "error", err)
+		return 0, err
 	}
+	userID, err := g.userFilter(filter.UserID)
+	if err != nil {
+		log.Info("aggregate over another user rejected")
+		return 0, err
+	}
+	filter.UserID = userID
 
-	// Map app DTO → SQLC params
-	params := queries.AggregateCostParams{
-		Column1: func() uuid.UUID {
-			if filter.UserID != nil {
-				return *filter.UserID
-			}
-			return uuid.Nil
-		}(),
-		Column2: func() string {
-			if filter.ServiceName != nil {
-				return *filter.ServiceName
-			}
-			return ""
-		}(),
-		StartDate: filter.StartPeriod,
-		EndDate:   sql.NullTime{Time: filter.EndPeriod, Valid: true},
+	if err := validatePeriod(log, filter); err != nil {
+		return 0, err
+	}
+
+	currency, err := s.normalizeCurrency(filter.Currency)
+	if err != nil {
+		log.Error("unsupported report currency", "currency", filter.Currency)
+		return 0, err
 	}
 
-	// Call repo.AggregateCost (returns interface{})
-	raw, err := s.repo.AggregateCost(ctx, params)
+	// Charges (or monthly equivalents) inside the window, summed per currency by the repo
+	rows, err := s.repo.AggregateCost(ctx, filter)
 	if err != nil {
 		log.Error("repo.AggregateCost failed", "error", err)
 		return 0, fmt.Errorf("failed to aggregate subscription cost: %w", err)
 	}
 
-	// Cast to int64 (Postgres COALESCE SUM gives int64), then to int32
-	total64, ok := raw.(int64)
-	if !ok {
-		return 0, fmt.Errorf("invalid aggregate type: %T", raw)
+	var total int64
+	for _, row := range rows {
+		converted, err := s.rates.Convert(row.Total, row.Currency, currency)
+		if err != nil {
+			log.Error("currency conversion failed", "from", row.Currency, "to", currency, "error", err)
+			return 0, fmt.Errorf("failed to convert subscription cost: %w", err)
+		}
+		total += converted
 	}
-	total := int32(total64)
 
-	log.Info("subscription cost aggregated", "total", total)
+	log.Info("subscription cost aggregated", "total", total, "currency", currency)
 	return total, nil
 }
 
-func mapToDomain(sub queries.Subscription) *domain.Subscription {
-	var endDate *time.Time
-	if sub.EndDate.Valid {
-		endDate = &sub.EndDate.Time
+func (s *service) AggregateGrouped(
+	ctx context.Context,
+	filter appdto.AggregationFilter,
+) ([]*appdto.CostBucket, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "AggregateGrouped", "filter", filter)
+	log.Debug("aggregating subscriptions by group")
+
+	g, err := authorize(ctx, ActionAggregate)
+	if err != nil {
+		log.Info("aggregate rejected by policy", "error", err)
+		return nil, err
 	}
-	return &domain.Subscription{
-		ID:          sub.ID,
-		ServiceName: sub.ServiceName,
-		UserID:      sub.UserID,
-		StartDate:   sub.StartDate,
-		EndDate:     endDate,
-		Price:       sub.Price,
+	userID, err := g.userFilter(filter.UserID)
+	if err != nil {
+		log.Info("aggregate over another user rejected")
+		return nil, err
+	}
+	filter.UserID = userID
+
+	if err := validatePeriod(log, filter); err != nil {
+		return nil, err
+	}
+	if len(filter.GroupBy) == 0 {
+		log.Error("group_by is required")
+		return nil, invalidField("group_by", "is required")
+	}
+	currency, err := s.normalizeCurrency(filter.Currency)
+	if err != nil {
+		log.Error("unsupported report currency", "currency", filter.Currency)
+		return nil, err
+	}
+
+	for _, field := range filter.GroupBy {
+		switch field {
+		case appdto.GroupByServiceName, appdto.GroupByUserID, appdto.GroupByMonth:
+		default:
+			log.Error("unknown group_by field", "field", field)
+			return nil, invalidField("group_by", fmt.Sprintf("has unknown field %q", field))
+		}
+	}
+
+	rows, err := s.repo.AggregateCostGrouped(ctx, filter)
+	if err != nil {
+		log.Error("repo.AggregateCostGrouped failed", "error", err)
+		return nil, fmt.Errorf("failed to aggregate subscription cost: %w", err)
+	}
+
+	// Rows are ordered by bucket key and then currency, so the per-currency
+	// rows of one bucket are adjacent and can be merged after conversion.
+	var buckets []*appdto.CostBucket
+	var last *appdto.CostBucket
+	for _, row := range rows {
+		converted, err := s.rates.Convert(row.Total, row.Currency, currency)
+		if err != nil {
+			log.Error("currency conversion failed", "from", row.Currency, "to", currency, "error", err)
+			return nil, fmt.Errorf("failed to convert subscription cost: %w", err)
+		}
+
+		b := &appdto.CostBucket{
+			ServiceName: row.ServiceName,
+			UserID:      row.UserID,
+			Month:       row.Month,
+		}
+		if last == nil || !sameBucket(last, b) {
+			buckets = append(buckets, b)
+			last = b
+		}
+		last.Total += converted
+		last.Subscriptions += row.Subscriptions
+	}
+
+	log.Info("subscription cost aggregated by group", "buckets", len(buckets))
+	return buckets, nil
+}
+
+func (s *service) TimeSeries(
+	ctx context.Context,
+	filter appdto.AggregationFilter,
+) ([]*appdto.MonthlyCost, error) {
+	log := logger.FromContext(ctx, s.log).With("service", "TimeSeries", "filter", filter)
+	log.Debug("building monthly cost series")
+
+	if err := validatePeriod(log, filter); err != nil {
+		return nil, err
+	}
+
+	filter.GroupBy = []appdto.GroupField{appdto.GroupByMonth}
+	buckets, err := s.AggregateGrouped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]int64, len(buckets))
+	for _, b := range buckets {
+		totals[b.Month.Format("2006-01")] = b.Total
+	}
+
+	// Emit every month of the window, including those without spend
+	start := time.Date(filter.StartPeriod.Year(), filter.StartPeriod.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var series []*appdto.MonthlyCost
+	for m := start; !m.After(filter.EndPeriod); m = m.AddDate(0, 1, 0) {
+		series = append(series, &appdto.MonthlyCost{
+			Month: m,
+			Total: totals[m.Format("2006-01")],
+		})
+	}
+
+	log.Info("monthly cost series built", "points", len(series))
+	return series, nil
+}
+
+// recordEvent appends an audit entry using repo, which should be the
+// transaction the change itself was written in.
+func (s *service) recordEvent(
+	ctx context.Context,
+	repo SubscriptionRepository,
+	id uuid.UUID,
+	action domain.EventAction,
+	before, after *domain.Subscription,
+) error {
+	event := &domain.SubscriptionEvent{
+		ID:             uuid.New(),
+		SubscriptionID: id,
+		Action:         action,
+		Actor:          ActorFromContext(ctx),
+		Before:         before,
+		After:          after,
+		CreatedAt:      time.Now(),
+	}
+	if err := repo.CreateEvent(ctx, event); err != nil {
+		logger.FromContext(ctx, s.log).Error("repo.CreateEvent failed", "id", id, "action", action, "error", err)
+		return fmt.Errorf("failed to record subscription event: %w", err)
+	}
+	return nil
+}
+
+// validatePeriod checks the window of a cost report: it must not run
+// backwards or span more than MaxAggregateMonths months.
+func validatePeriod(log *slog.Logger, filter appdto.AggregationFilter) error {
+	start, end := filter.StartPeriod, filter.EndPeriod
+	if start.After(end) {
+		log.Error("start_period cannot be after end_period", "start", start, "end", end)
+		return invalidField("end_period", "must not be before start_period")
+	}
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month()) + 1
+	if months > MaxAggregateMonths {
+		log.Error("aggregation window too long", "months", months)
+		return invalidField("end_period", fmt.Sprintf("must leave a window of at most %d months", MaxAggregateMonths))
+	}
+	return nil
+}
+
+// normalizeCurrency upper-cases an ISO 4217 code, falls back to
+// DefaultCurrency when empty and rejects codes without an exchange rate.
+func (s *service) normalizeCurrency(code string) (string, error) {
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+	code = strings.ToUpper(code)
+	if !s.rates.Supports(code) {
+		return "", invalidField("currency", fmt.Sprintf("%q is not supported", code))
+	}
+	return code, nil
+}
+
+func sameBucket(a, b *appdto.CostBucket) bool {
+	if (a.ServiceName == nil) != (b.ServiceName == nil) ||
+		(a.ServiceName != nil && *a.ServiceName != *b.ServiceName) {
+		return false
+	}
+	if (a.UserID == nil) != (b.UserID == nil) ||
+		(a.UserID != nil && *a.UserID != *b.UserID) {
+		return false
+	}
+	if (a.Month == nil) != (b.Month == nil) ||
+		(a.Month != nil && !a.Month.Equal(*b.Month)) {
+		return false
 	}
+	return true
 }
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// DefaultTenant owns the rows that predate tenants and the requests of
+// callers whose credentials name none.
+const DefaultTenant = "default"
+
+// ErrNoTenant means an operation that is scoped to a tenant ran without one.
+// Repositories refuse such calls rather than fall back to every tenant.
+var ErrNoTenant = errors.New("no tenant in context")
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidTenantID reports whether id can name a tenant: 1 to 63 lowercase
+// letters, digits, '-' or '_', starting with a letter or digit.
+func ValidTenantID(id string) bool {
+	return tenantIDPattern.MatchString(id)
+}
+
+type tenantKey struct{}
+
+// WithTenant scopes every repository call made with ctx to one tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set by WithTenant, or ErrNoTenant.
+func TenantFromContext(ctx context.Context) (string, error) {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+		return tenant, nil
+	}
+	return "", ErrNoTenant
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyScope is a permission granted to an API key.
+type APIKeyScope string
+
+const (
+	ScopeRead      APIKeyScope = "read"      // view subscriptions, their history and the trash
+	ScopeWrite     APIKeyScope = "write"     // create, import, change, delete and restore
+	ScopeAggregate APIKeyScope = "aggregate" // cost reports
+	ScopeAdmin     APIKeyScope = "admin"     // every scope, plus managing API keys
+)
+
+func (s APIKeyScope) Valid() bool {
+	switch s {
+	case ScopeRead, ScopeWrite, ScopeAggregate, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// APIKey identifies a service-to-service caller. Only a hash of its secret
+// is stored, so the secret itself is shown once, when the key is issued.
+type APIKey struct {
+	ID        uuid.UUID
+	TenantID  string // the tenant the key's caller acts in
+	Name      string
+	Prefix    string // first characters of the secret, to tell keys apart
+	Scopes    []APIKeyScope
+	ExpiresAt *time.Time // nil for keys that never expire
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// Allows reports whether the key grants scope; admin grants every scope.
+func (k *APIKey) Allows(scope APIKeyScope) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// Active reports whether the key may be used at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventAction string
+
+const (
+	EventCreated  EventAction = "create"
+	EventUpdated  EventAction = "update"
+	EventDeleted  EventAction = "delete"
+	EventRestored EventAction = "restore"
+)
+
+// SubscriptionEvent is one entry of a subscription's change history.
+// Before is nil for creations, After is nil for deletions.
+type SubscriptionEvent struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+	Action         EventAction
+	Actor          string
+	Before         *Subscription
+	After          *Subscription
+	CreatedAt      time.Time
+}
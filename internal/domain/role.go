@@ -0,0 +1,18 @@
+package domain
+
+// Role is what a user may do with subscriptions.
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"   // manage every user's subscriptions
+	RoleAnalyst Role = "analyst" // aggregate and export across users, nothing else
+	RoleMember  Role = "member"  // work with their own subscriptions only
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleAnalyst, RoleMember:
+		return true
+	}
+	return false
+}
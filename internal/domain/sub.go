@@ -7,10 +7,33 @@ import (
 )
 
 type Subscription struct {
-	ID          uuid.UUID
-	ServiceName string
-	Price       int32
-	UserID      uuid.UUID
-	StartDate   time.Time
-	EndDate     *time.Time
+	ID            uuid.UUID
+	ServiceName   string
+	Price         int32
+	Currency      string // ISO 4217
+	BillingPeriod BillingPeriod
+	UserID        uuid.UUID
+	StartDate     time.Time
+	EndDate       *time.Time
+	DeletedAt     *time.Time // set while the subscription is in the trash
+	Version       int32      // incremented on every update
+}
+
+// BillingPeriod is how often a subscription renews; the price is charged
+// once per period, counted from StartDate.
+type BillingPeriod string
+
+const (
+	BillingWeekly    BillingPeriod = "weekly"
+	BillingMonthly   BillingPeriod = "monthly"
+	BillingQuarterly BillingPeriod = "quarterly"
+	BillingYearly    BillingPeriod = "yearly"
+)
+
+func (p BillingPeriod) Valid() bool {
+	switch p {
+	case BillingWeekly, BillingMonthly, BillingQuarterly, BillingYearly:
+		return true
+	}
+	return false
 }
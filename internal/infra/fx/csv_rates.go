@@ -0,0 +1,87 @@
+package fx
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+)
+
+// Rates is a static exchange-rate table. Every currency is quoted against a
+// common base: rate is how many base units one unit of the currency buys.
+type Rates struct {
+	rates map[string]float64
+}
+
+// LoadCSV reads a "currency,rate" file (header row required), e.g.
+//
+//	currency,rate
+//	RUB,1
+//	USD,90.5
+func LoadCSV(path string) (*Rates, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+
+	if _, err := r.Read(); err != nil {
+		return nil, fmt.Errorf("read rates header: %w", err)
+	}
+
+	rates := make(map[string]float64)
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read rates: %w", err)
+		}
+
+		code := strings.ToUpper(strings.TrimSpace(rec[0]))
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rec[1]), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %q", code, rec[1])
+		}
+		rates[code] = rate
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("no exchange rates in %s", path)
+	}
+	return &Rates{rates: rates}, nil
+}
+
+var _ app.ExchangeRates = (*Rates)(nil)
+
+func (r *Rates) Supports(currency string) bool {
+	_, ok := r.rates[currency]
+	return ok
+}
+
+// Convert rounds the result to the nearest whole unit.
+func (r *Rates) Convert(amount int64, from, to string) (int64, error) {
+	if from == to {
+		return amount, nil
+	}
+	fromRate, ok := r.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s", from)
+	}
+	toRate, ok := r.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s", to)
+	}
+	return int64(math.Round(float64(amount) * fromRate / toRate)), nil
+}
@@ -0,0 +1,267 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Monthly-equivalent factors exactly as Postgres computes 52.0 / 12, 1.0 / 3
+// and 1.0 / 12 in NUMERIC, so rounded totals agree with the SQL repository.
+var (
+	weeklyPerMonth    = mustRat("4.3333333333333333")
+	quarterlyPerMonth = mustRat("0.33333333333333333333")
+	yearlyPerMonth    = mustRat("0.08333333333333333333")
+)
+
+// AggregateCost computes the total subscription cost per currency based on filters
+func (r *repo) AggregateCost(ctx context.Context, filter appdto.AggregationFilter) ([]appdto.CurrencyCost, error) {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.lock()()
+
+	totals := make(map[string]*big.Rat)
+	r.eachCharge(tenant, filter, func(sub domain.Subscription, _ time.Time, charge *big.Rat) {
+		total, ok := totals[sub.Currency]
+		if !ok {
+			total = new(big.Rat)
+			totals[sub.Currency] = total
+		}
+		total.Add(total, charge)
+	})
+
+	rows := make([]appdto.CurrencyCost, 0, len(totals))
+	for currency, total := range totals {
+		rows = append(rows, appdto.CurrencyCost{Currency: currency, Total: roundRat(total)})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Currency < rows[j].Currency })
+	return rows, nil
+}
+
+// AggregateCostGrouped computes cost buckets for the requested grouping
+func (r *repo) AggregateCostGrouped(ctx context.Context, filter appdto.AggregationFilter) ([]appdto.CurrencyBucket, error) {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.lock()()
+
+	var byServiceName, byUserID, byMonth bool
+	for _, field := range filter.GroupBy {
+		switch field {
+		case appdto.GroupByServiceName:
+			byServiceName = true
+		case appdto.GroupByUserID:
+			byUserID = true
+		case appdto.GroupByMonth:
+			byMonth = true
+		}
+	}
+
+	// Zero values stand in for the NULLs of ungrouped columns
+	type key struct {
+		serviceName string
+		userID      uuid.UUID
+		month       time.Time
+		currency    string
+	}
+	type bucket struct {
+		total *big.Rat
+		subs  map[uuid.UUID]struct{}
+	}
+
+	buckets := make(map[key]*bucket)
+	r.eachCharge(tenant, filter, func(sub domain.Subscription, month time.Time, charge *big.Rat) {
+		k := key{currency: sub.Currency}
+		if byServiceName {
+			k.serviceName = sub.ServiceName
+		}
+		if byUserID {
+			k.userID = sub.UserID
+		}
+		if byMonth {
+			k.month = month
+		}
+		b, ok := buckets[k]
+		if !ok {
+			b = &bucket{total: new(big.Rat), subs: make(map[uuid.UUID]struct{})}
+			buckets[k] = b
+		}
+		b.total.Add(b.total, charge)
+		b.subs[sub.ID] = struct{}{}
+	})
+
+	keys := make([]key, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	// ORDER BY 1, 2, 3, 4; ungrouped columns are equal in every row, so only
+	// grouped ones decide. Service names compare bytewise, not by collation.
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.serviceName != b.serviceName {
+			return a.serviceName < b.serviceName
+		}
+		if c := bytes.Compare(a.userID[:], b.userID[:]); c != 0 {
+			return c < 0
+		}
+		if !a.month.Equal(b.month) {
+			return a.month.Before(b.month)
+		}
+		return a.currency < b.currency
+	})
+
+	rows := make([]appdto.CurrencyBucket, 0, len(keys))
+	for _, k := range keys {
+		row := appdto.CurrencyBucket{Currency: k.currency}
+		if byServiceName {
+			row.ServiceName = &k.serviceName
+		}
+		if byUserID {
+			row.UserID = &k.userID
+		}
+		if byMonth {
+			row.Month = &k.month
+		}
+		row.Total = roundRat(buckets[k].total)
+		row.Subscriptions = int64(len(buckets[k].subs))
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// eachCharge calls fn for every (subscription, month) pair the aggregate
+// queries join: live subscriptions of the tenant matching the filters, for
+// each month of the window they are active in.
+func (r *repo) eachCharge(tenant string, filter appdto.AggregationFilter, fn func(sub domain.Subscription, month time.Time, charge *big.Rat)) {
+	months := monthSeries(toDate(filter.StartPeriod), toDate(filter.EndPeriod))
+	for id, sub := range r.s.subs {
+		if r.s.tenants[id] != tenant || sub.DeletedAt != nil {
+			continue
+		}
+		if filter.UserID != nil && sub.UserID != *filter.UserID {
+			continue
+		}
+		if filter.ServiceName != nil && sub.ServiceName != *filter.ServiceName {
+			continue
+		}
+		for _, month := range months {
+			if activeIn(sub, month) {
+				fn(sub, month, monthCharge(sub.Price, sub.BillingPeriod, sub.StartDate, sub.EndDate, month, filter.Normalize))
+			}
+		}
+	}
+}
+
+// monthSeries mirrors generate_series(start, end, interval '1 month').
+func monthSeries(start, end time.Time) []time.Time {
+	var months []time.Time
+	for m := start; !m.After(end); m = addMonth(m) {
+		months = append(months, m)
+	}
+	return months
+}
+
+// activeIn is the join condition of the aggregate queries.
+func activeIn(sub domain.Subscription, month time.Time) bool {
+	firstOfMonth := time.Date(sub.StartDate.Year(), sub.StartDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if firstOfMonth.After(month) {
+		return false
+	}
+	return sub.EndDate == nil || !sub.EndDate.Before(month)
+}
+
+// monthCharge is subscription_month_charge from the schema.
+func monthCharge(price int32, period domain.BillingPeriod, start time.Time, end *time.Time, month time.Time, normalize bool) *big.Rat {
+	charge := new(big.Rat).SetInt64(int64(price))
+	if normalize {
+		switch period {
+		case domain.BillingWeekly:
+			return charge.Mul(charge, weeklyPerMonth)
+		case domain.BillingQuarterly:
+			return charge.Mul(charge, quarterlyPerMonth)
+		case domain.BillingYearly:
+			return charge.Mul(charge, yearlyPerMonth)
+		}
+		return charge
+	}
+	return charge.Mul(charge, new(big.Rat).SetInt64(renewals(period, start, end, month)))
+}
+
+// renewals counts the billing dates, counted from start, that fall in the
+// month beginning at month and not after end, if set.
+func renewals(period domain.BillingPeriod, start time.Time, end *time.Time, month time.Time) int64 {
+	switch period {
+	case domain.BillingWeekly:
+		from := month
+		if start.After(from) {
+			from = start
+		}
+		upto := addMonth(month)
+		if end != nil {
+			if dayAfter := toDate(*end).AddDate(0, 0, 1); dayAfter.Before(upto) {
+				upto = dayAfter
+			}
+		}
+		return (days(start, upto)+6)/7 - (days(start, from)+6)/7
+	case domain.BillingQuarterly:
+		diff := (month.Year()-start.Year())*12 + int(month.Month()) - int(start.Month())
+		if diff%3 == 0 {
+			return 1
+		}
+		return 0
+	case domain.BillingYearly:
+		if month.Month() == start.Month() {
+			return 1
+		}
+		return 0
+	}
+	return 1
+}
+
+// days is the DATE subtraction b - a.
+func days(a, b time.Time) int64 {
+	return int64(b.Sub(a) / (24 * time.Hour))
+}
+
+// addMonth adds one month the way Postgres interval arithmetic does: the day
+// is clamped to the end of a shorter month instead of overflowing.
+func addMonth(t time.Time) time.Time {
+	firstOfNext := time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := firstOfNext.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(firstOfNext.Year(), firstOfNext.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// roundRat is ROUND(numeric): to the nearest integer, halves away from zero.
+func roundRat(r *big.Rat) int64 {
+	num := new(big.Int).Abs(r.Num())
+	q, m := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if m.Lsh(m, 1).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
+
+func mustRat(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic("invalid rational " + s)
+	}
+	return r
+}
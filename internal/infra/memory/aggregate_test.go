@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func datePtr(y int, m time.Month, d int) *time.Time {
+	t := date(y, m, d)
+	return &t
+}
+
+func TestRenewals(t *testing.T) {
+	tests := []struct {
+		name   string
+		period domain.BillingPeriod
+		start  time.Time
+		end    *time.Time
+		month  time.Time
+		want   int64
+	}{
+		{"monthly, first month", domain.BillingMonthly, date(2025, 1, 15), nil, date(2025, 1, 1), 1},
+		{"monthly, end month", domain.BillingMonthly, date(2025, 1, 1), datePtr(2025, 3, 1), date(2025, 3, 1), 1},
+
+		// From 2025-01-01 a weekly plan renews on the 1st, 8th, 15th, 22nd and
+		// 29th of January, then on the 5th, 12th, 19th and 26th of February.
+		{"weekly, five renewals", domain.BillingWeekly, date(2025, 1, 1), nil, date(2025, 1, 1), 5},
+		{"weekly, across the month boundary", domain.BillingWeekly, date(2025, 1, 1), nil, date(2025, 2, 1), 4},
+		{"weekly, starting mid-month", domain.BillingWeekly, date(2025, 1, 20), nil, date(2025, 1, 1), 2},
+		{"weekly, starting on the last day", domain.BillingWeekly, date(2025, 1, 31), nil, date(2025, 1, 1), 1},
+		{"weekly, ending before the first renewal of the month", domain.BillingWeekly, date(2025, 1, 1), datePtr(2025, 2, 4), date(2025, 2, 1), 0},
+		{"weekly, ending on a renewal", domain.BillingWeekly, date(2025, 1, 1), datePtr(2025, 2, 5), date(2025, 2, 1), 1},
+		{"weekly, ending mid-month", domain.BillingWeekly, date(2025, 1, 1), datePtr(2025, 2, 13), date(2025, 2, 1), 2},
+		{"weekly, ending after the month", domain.BillingWeekly, date(2025, 1, 1), datePtr(2025, 4, 1), date(2025, 2, 1), 4},
+
+		{"quarterly, renewal month", domain.BillingQuarterly, date(2024, 11, 1), nil, date(2025, 2, 1), 1},
+		{"quarterly, between renewals", domain.BillingQuarterly, date(2024, 11, 1), nil, date(2025, 3, 1), 0},
+
+		{"yearly, first month", domain.BillingYearly, date(2024, 3, 10), nil, date(2024, 3, 1), 1},
+		{"yearly, anniversary", domain.BillingYearly, date(2024, 3, 10), nil, date(2025, 3, 1), 1},
+		{"yearly, other month", domain.BillingYearly, date(2024, 3, 10), nil, date(2025, 4, 1), 0},
+		{"yearly, across the year boundary", domain.BillingYearly, date(2024, 12, 1), nil, date(2025, 1, 1), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renewals(tt.period, tt.start, tt.end, tt.month); got != tt.want {
+				t.Errorf("renewals() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateCostGroupedByMonth(t *testing.T) {
+	tests := []struct {
+		name  string
+		sub   domain.Subscription
+		from  time.Time
+		to    time.Time
+		norm  bool
+		wantM map[string]int64 // total per month, as YYYY-MM
+	}{
+		{
+			name:  "monthly, inclusive start and end months",
+			sub:   domain.Subscription{Price: 100, BillingPeriod: domain.BillingMonthly, StartDate: date(2025, 2, 1), EndDate: datePtr(2025, 4, 1)},
+			from:  date(2025, 1, 1),
+			to:    date(2025, 5, 1),
+			wantM: map[string]int64{"2025-02": 100, "2025-03": 100, "2025-04": 100},
+		},
+		{
+			name:  "monthly, open-ended",
+			sub:   domain.Subscription{Price: 100, BillingPeriod: domain.BillingMonthly, StartDate: date(2024, 12, 1)},
+			from:  date(2025, 1, 1),
+			to:    date(2025, 2, 1),
+			wantM: map[string]int64{"2025-01": 100, "2025-02": 100},
+		},
+		{
+			name:  "weekly, ending in the first days of a month",
+			sub:   domain.Subscription{Price: 10, BillingPeriod: domain.BillingWeekly, StartDate: date(2025, 1, 1), EndDate: datePtr(2025, 2, 1)},
+			from:  date(2025, 1, 1),
+			to:    date(2025, 3, 1),
+			wantM: map[string]int64{"2025-01": 50, "2025-02": 0},
+		},
+		{
+			name:  "weekly, normalized",
+			sub:   domain.Subscription{Price: 100, BillingPeriod: domain.BillingWeekly, StartDate: date(2025, 1, 1)},
+			from:  date(2025, 1, 1),
+			to:    date(2025, 1, 1),
+			norm:  true,
+			wantM: map[string]int64{"2025-01": 433},
+		},
+		{
+			name:  "yearly, charged in the renewal month only",
+			sub:   domain.Subscription{Price: 1200, BillingPeriod: domain.BillingYearly, StartDate: date(2024, 3, 1)},
+			from:  date(2025, 2, 1),
+			to:    date(2025, 4, 1),
+			wantM: map[string]int64{"2025-02": 0, "2025-03": 1200, "2025-04": 0},
+		},
+		{
+			name:  "yearly, normalized",
+			sub:   domain.Subscription{Price: 1200, BillingPeriod: domain.BillingYearly, StartDate: date(2024, 3, 1)},
+			from:  date(2025, 1, 1),
+			to:    date(2025, 1, 1),
+			norm:  true,
+			wantM: map[string]int64{"2025-01": 100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := app.WithTenant(context.Background(), "t1")
+			r := NewSubscriptionRepo()
+			sub := tt.sub
+			sub.ID = uuid.New()
+			sub.UserID = uuid.New()
+			sub.ServiceName = "svc"
+			sub.Currency = "RUB"
+			if err := r.Create(ctx, &sub); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			rows, err := r.AggregateCostGrouped(ctx, appdto.AggregationFilter{
+				StartPeriod: tt.from,
+				EndPeriod:   tt.to,
+				Normalize:   tt.norm,
+				GroupBy:     []appdto.GroupField{appdto.GroupByMonth},
+			})
+			if err != nil {
+				t.Fatalf("AggregateCostGrouped: %v", err)
+			}
+			got := make(map[string]int64, len(rows))
+			for _, row := range rows {
+				got[row.Month.Format("2006-01")] = row.Total
+			}
+			if len(got) != len(tt.wantM) {
+				t.Fatalf("months = %v, want %v", got, tt.wantM)
+			}
+			for month, want := range tt.wantM {
+				if got[month] != want {
+					t.Errorf("total for %s = %d, want %d", month, got[month], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateCostIsolatesTenants(t *testing.T) {
+	r := NewSubscriptionRepo()
+	for _, tenant := range []string{"t1", "t2"} {
+		sub := &domain.Subscription{
+			ID: uuid.New(), UserID: uuid.New(), ServiceName: "svc", Price: 100,
+			Currency: "RUB", BillingPeriod: domain.BillingMonthly, StartDate: date(2025, 1, 1),
+		}
+		if err := r.Create(app.WithTenant(context.Background(), tenant), sub); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	rows, err := r.AggregateCost(app.WithTenant(context.Background(), "t1"), appdto.AggregationFilter{
+		StartPeriod: date(2025, 1, 1),
+		EndPeriod:   date(2025, 1, 1),
+	})
+	if err != nil {
+		t.Fatalf("AggregateCost: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Total != 100 {
+		t.Errorf("rows = %+v, want one RUB row of 100", rows)
+	}
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+type apiKeyRepo struct {
+	mu     sync.Mutex
+	keys   map[uuid.UUID]domain.APIKey
+	hashes map[string]uuid.UUID
+}
+
+func NewAPIKeyRepo() app.APIKeyRepository {
+	return &apiKeyRepo{
+		keys:   make(map[uuid.UUID]domain.APIKey),
+		hashes: make(map[string]uuid.UUID),
+	}
+}
+
+func (r *apiKeyRepo) CreateAPIKey(ctx context.Context, key *domain.APIKey, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[key.ID]; ok {
+		return fmt.Errorf("duplicate api key id %s", key.ID)
+	}
+	if _, ok := r.hashes[hash]; ok {
+		return fmt.Errorf("duplicate api key hash")
+	}
+	r.keys[key.ID] = cloneAPIKey(*key)
+	r.hashes[hash] = key.ID
+	return nil
+}
+
+func (r *apiKeyRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.hashes[hash]
+	if !ok {
+		return nil, app.ErrAPIKeyNotFound
+	}
+	key := cloneAPIKey(r.keys[id])
+	return &key, nil
+}
+
+func (r *apiKeyRepo) ListAPIKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var keys []*domain.APIKey
+	for _, k := range r.keys {
+		if k.TenantID != tenant {
+			continue
+		}
+		key := cloneAPIKey(k)
+		keys = append(keys, &key)
+	}
+	// ORDER BY created_at DESC, id DESC
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+		return bytes.Compare(keys[i].ID[:], keys[j].ID[:]) > 0
+	})
+	return keys, nil
+}
+
+func (r *apiKeyRepo) RevokeAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.TenantID != tenant || key.RevokedAt != nil {
+		return app.ErrAPIKeyNotFound
+	}
+	key.RevokedAt = &at
+	r.keys[id] = key
+	return nil
+}
+
+// cloneAPIKey copies the slice so callers cannot change stored keys.
+func cloneAPIKey(k domain.APIKey) domain.APIKey {
+	k.Scopes = slices.Clone(k.Scopes)
+	return k
+}
@@ -0,0 +1,456 @@
+// Package memory is an in-process SubscriptionRepository for demos and tests.
+// It follows the semantics of the Postgres queries, including their error
+// values, but nothing survives a restart.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// store is the state shared by a repo and the transactions it opens.
+type store struct {
+	mu      sync.Mutex
+	subs    map[uuid.UUID]domain.Subscription
+	tenants map[uuid.UUID]string // tenant of each subscription, like its tenant_id column
+	events  []event
+	keys    map[idempotencyKey]appdto.IdempotencyRecord
+}
+
+type event struct {
+	tenant string
+	domain.SubscriptionEvent
+}
+
+// idempotencyKey mirrors the (tenant_id, key) primary key.
+type idempotencyKey struct {
+	tenant string
+	key    string
+}
+
+type repo struct {
+	s    *store
+	inTx bool // the store lock is already held by WithTx
+}
+
+func NewSubscriptionRepo() app.SubscriptionRepository {
+	return &repo{s: &store{
+		subs:    make(map[uuid.UUID]domain.Subscription),
+		tenants: make(map[uuid.UUID]string),
+		keys:    make(map[idempotencyKey]appdto.IdempotencyRecord),
+	}}
+}
+
+// lock takes the store lock unless a transaction already holds it and
+// returns the matching unlock.
+func (r *repo) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.s.mu.Lock()
+	return r.s.mu.Unlock
+}
+
+// WithTx runs fn with the store locked and rolls its changes back on error.
+// Transactions are serialized, which is stricter than Postgres but keeps the
+// same all-or-nothing outcome.
+func (r *repo) WithTx(ctx context.Context, fn func(repo app.SubscriptionRepository) error) error {
+	// Already inside a transaction: join it
+	if r.inTx {
+		return fn(r)
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	subs := maps.Clone(r.s.subs)
+	tenants := maps.Clone(r.s.tenants)
+	events := len(r.s.events) // events are append-only
+	keys := maps.Clone(r.s.keys)
+
+	if err := fn(&repo{s: r.s, inTx: true}); err != nil {
+		r.s.subs = subs
+		r.s.tenants = tenants
+		r.s.events = r.s.events[:events]
+		r.s.keys = keys
+		return err
+	}
+	return nil
+}
+
+func (r *repo) Create(ctx context.Context, sub *domain.Subscription) error {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.lock()()
+
+	if _, ok := r.s.subs[sub.ID]; ok {
+		return fmt.Errorf("duplicate subscription id %s", sub.ID)
+	}
+	stored := *sub
+	stored.StartDate = toDate(sub.StartDate)
+	stored.EndDate = toDatePtr(sub.EndDate)
+	stored.DeletedAt = nil
+	stored.Version = 1
+	r.s.subs[stored.ID] = stored
+	r.s.tenants[stored.ID] = tenant
+	return nil
+}
+
+func (r *repo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.lock()()
+
+	sub, ok := r.owned(tenant, id)
+	if !ok || sub.DeletedAt != nil {
+		return nil, app.ErrNotFound
+	}
+	return clone(sub), nil
+}
+
+func (r *repo) List(ctx context.Context, userID *uuid.UUID, serviceName *string, after *appdto.Keyset, limit int32) ([]*domain.Subscription, error) {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.lock()()
+
+	var match *regexp.Regexp
+	if serviceName != nil {
+		match = ilikeContains(*serviceName)
+	}
+
+	var out []domain.Subscription
+	for id, sub := range r.s.subs {
+		if r.s.tenants[id] != tenant || sub.DeletedAt != nil {
+			continue
+		}
+		if userID != nil && sub.UserID != *userID {
+			continue
+		}
+		if match != nil && !match.MatchString(sub.ServiceName) {
+			continue
+		}
+		if after != nil && !keysetLess(sub.StartDate, sub.ID, toDate(after.StartDate), after.ID) {
+			continue
+		}
+		out = append(out, sub)
+	}
+
+	// ORDER BY start_date DESC, id DESC
+	sort.Slice(out, func(i, j int) bool {
+		return keysetLess(out[j].StartDate, out[j].ID, out[i].StartDate, out[i].ID)
+	})
+	return limitRows(out, limit), nil
+}
+
+func (r *repo) Update(ctx context.Context, sub *domain.Subscription, version int32) error {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.lock()()
+
+	stored, ok := r.owned(tenant, sub.ID)
+	if !ok || stored.DeletedAt != nil {
+		return app.ErrNotFound
+	}
+	if stored.Version != version {
+		return app.ErrConflict
+	}
+	stored.ServiceName = sub.ServiceName
+	stored.Price = sub.Price
+	stored.StartDate = toDate(sub.StartDate)
+	stored.EndDate = toDatePtr(sub.EndDate)
+	stored.Currency = sub.Currency
+	stored.BillingPeriod = sub.BillingPeriod
+	stored.Version++
+	r.s.subs[sub.ID] = stored
+	return nil
+}
+
+// Delete moves the subscription to the trash
+func (r *repo) Delete(ctx context.Context, id uuid.UUID) error {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.lock()()
+
+	sub, ok := r.owned(tenant, id)
+	if !ok || sub.DeletedAt != nil {
+		return app.ErrNotFound
+	}
+	now := time.Now()
+	sub.DeletedAt = &now
+	r.s.subs[id] = sub
+	return nil
+}
+
+// Restore takes a subscription out of the trash
+func (r *repo) Restore(ctx context.Context, id uuid.UUID) error {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.lock()()
+
+	sub, ok := r.owned(tenant, id)
+	if !ok || sub.DeletedAt == nil {
+		return app.ErrNotFound
+	}
+	sub.DeletedAt = nil
+	r.s.subs[id] = sub
+	return nil
+}
+
+func (r *repo) ListDeleted(ctx context.Context, userID *uuid.UUID, limit int32) ([]*domain.Subscription, error) {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.lock()()
+
+	var out []domain.Subscription
+	for id, sub := range r.s.subs {
+		if r.s.tenants[id] != tenant || sub.DeletedAt == nil {
+			continue
+		}
+		if userID != nil && sub.UserID != *userID {
+			continue
+		}
+		out = append(out, sub)
+	}
+
+	// ORDER BY deleted_at DESC, id DESC
+	sort.Slice(out, func(i, j int) bool {
+		a, b := *out[i].DeletedAt, *out[j].DeletedAt
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return bytes.Compare(out[i].ID[:], out[j].ID[:]) > 0
+	})
+	return limitRows(out, limit), nil
+}
+
+// PurgeDeleted permanently removes subscriptions of every tenant trashed
+// before the given time
+func (r *repo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	defer r.lock()()
+
+	var n int64
+	for id, sub := range r.s.subs {
+		if sub.DeletedAt != nil && sub.DeletedAt.Before(before) {
+			delete(r.s.subs, id)
+			delete(r.s.tenants, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CreateEvent appends an entry to the subscription audit trail
+func (r *repo) CreateEvent(ctx context.Context, e *domain.SubscriptionEvent) error {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.lock()()
+
+	stored := event{tenant: tenant, SubscriptionEvent: *e}
+	stored.Before = clonePtr(e.Before)
+	stored.After = clonePtr(e.After)
+	r.s.events = append(r.s.events, stored)
+	return nil
+}
+
+func (r *repo) ListEvents(ctx context.Context, subscriptionID uuid.UUID) ([]*domain.SubscriptionEvent, error) {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.lock()()
+
+	var out []*domain.SubscriptionEvent
+	for _, e := range r.s.events {
+		if e.tenant == tenant && e.SubscriptionID == subscriptionID {
+			event := e.SubscriptionEvent
+			event.Before = clonePtr(e.Before)
+			event.After = clonePtr(e.After)
+			out = append(out, &event)
+		}
+	}
+
+	// ORDER BY created_at, id
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return bytes.Compare(out[i].ID[:], out[j].ID[:]) < 0
+	})
+	return out, nil
+}
+
+func (r *repo) ClaimIdempotencyKey(ctx context.Context, key, requestHash string) (bool, error) {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer r.lock()()
+
+	k := idempotencyKey{tenant: tenant, key: key}
+	if _, ok := r.s.keys[k]; ok {
+		return false, nil
+	}
+	r.s.keys[k] = appdto.IdempotencyRecord{Key: key, RequestHash: requestHash, CreatedAt: time.Now()}
+	return true, nil
+}
+
+func (r *repo) GetIdempotencyKey(ctx context.Context, key string) (*appdto.IdempotencyRecord, error) {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.lock()()
+
+	rec, ok := r.s.keys[idempotencyKey{tenant: tenant, key: key}]
+	if !ok {
+		return nil, app.ErrNotFound
+	}
+	rec.Response = clonePtr(rec.Response)
+	return &rec, nil
+}
+
+// SaveIdempotentResponse stores the result to replay for a claimed key
+func (r *repo) SaveIdempotentResponse(ctx context.Context, key string, sub *domain.Subscription) error {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.lock()()
+
+	k := idempotencyKey{tenant: tenant, key: key}
+	rec, ok := r.s.keys[k]
+	if !ok {
+		return nil // UPDATE of a missing row
+	}
+	rec.Response = clonePtr(sub)
+	r.s.keys[k] = rec
+	return nil
+}
+
+// PurgeIdempotencyKeys forgets idempotency keys of every tenant claimed
+// before the given time
+func (r *repo) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	defer r.lock()()
+
+	var n int64
+	for key, rec := range r.s.keys {
+		if rec.CreatedAt.Before(before) {
+			delete(r.s.keys, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// owned returns the subscription with id if it belongs to tenant.
+func (r *repo) owned(tenant string, id uuid.UUID) (domain.Subscription, bool) {
+	sub, ok := r.s.subs[id]
+	return sub, ok && r.s.tenants[id] == tenant
+}
+
+// keysetLess reports whether (startA, idA) < (startB, idB) as a row comparison.
+// Postgres orders uuids bytewise.
+func keysetLess(startA time.Time, idA uuid.UUID, startB time.Time, idB uuid.UUID) bool {
+	if !startA.Equal(startB) {
+		return startA.Before(startB)
+	}
+	return bytes.Compare(idA[:], idB[:]) < 0
+}
+
+// limitRows applies LIMIT and hands out copies of the stored rows.
+func limitRows(rows []domain.Subscription, limit int32) []*domain.Subscription {
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+	}
+	out := make([]*domain.Subscription, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, clone(row))
+	}
+	return out
+}
+
+// ilikeContains matches like service_name ILIKE '%' || pattern || '%', where
+// % and _ inside pattern are wildcards and backslash escapes.
+func ilikeContains(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)")
+	escaped := false
+	for _, c := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '%':
+			b.WriteString(".*")
+		case c == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return regexp.MustCompile(b.String())
+}
+
+// toDate drops the time of day, as storing into a DATE column does.
+func toDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func toDatePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	d := toDate(*t)
+	return &d
+}
+
+// clone copies a stored subscription so callers cannot modify the store
+// through its pointer fields.
+func clone(sub domain.Subscription) *domain.Subscription {
+	if sub.EndDate != nil {
+		end := *sub.EndDate
+		sub.EndDate = &end
+	}
+	if sub.DeletedAt != nil {
+		deleted := *sub.DeletedAt
+		sub.DeletedAt = &deleted
+	}
+	return &sub
+}
+
+func clonePtr(sub *domain.Subscription) *domain.Subscription {
+	if sub == nil {
+		return nil
+	}
+	return clone(*sub)
+}
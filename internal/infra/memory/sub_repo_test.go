@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// listAll pages through List with the keyset of each page's last row, the
+// way the service builds cursors. before runs ahead of every page but the
+// first.
+func listAll(t *testing.T, ctx context.Context, r app.SubscriptionRepository, limit int32, before func()) []uuid.UUID {
+	t.Helper()
+	var ids []uuid.UUID
+	var after *appdto.Keyset
+	for {
+		if after != nil && before != nil {
+			before()
+		}
+		page, err := r.List(ctx, nil, nil, after, limit)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		for _, sub := range page {
+			ids = append(ids, sub.ID)
+		}
+		if int32(len(page)) < limit {
+			return ids
+		}
+		last := page[len(page)-1]
+		after = &appdto.Keyset{StartDate: last.StartDate, ID: last.ID}
+	}
+}
+
+func TestListKeysetPaging(t *testing.T) {
+	ctx := app.WithTenant(context.Background(), "t1")
+	r := NewSubscriptionRepo()
+
+	// Several subscriptions share a start date, so the ID has to break ties
+	starts := []int{1, 1, 1, 2, 2, 3, 4, 4, 4, 5}
+	for _, month := range starts {
+		sub := &domain.Subscription{
+			ID: uuid.New(), UserID: uuid.New(), ServiceName: "svc", Price: 1,
+			Currency: "RUB", BillingPeriod: domain.BillingMonthly, StartDate: date(2025, time.Month(month), 1),
+		}
+		if err := r.Create(ctx, sub); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	want, err := r.List(ctx, nil, nil, nil, int32(len(starts)))
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for i := 1; i < len(want); i++ {
+		a, b := want[i-1], want[i]
+		if a.StartDate.Before(b.StartDate) || (a.StartDate.Equal(b.StartDate) && a.ID.String() < b.ID.String()) {
+			t.Fatalf("rows %d and %d are not in start_date DESC, id DESC order", i-1, i)
+		}
+	}
+
+	for _, limit := range []int32{1, 3, 4, int32(len(starts))} {
+		got := listAll(t, ctx, r, limit, nil)
+		if len(got) != len(want) {
+			t.Fatalf("limit %d: %d rows, want %d", limit, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i].ID {
+				t.Errorf("limit %d: row %d = %s, want %s", limit, i, got[i], want[i].ID)
+			}
+		}
+	}
+}
+
+func TestListKeysetPagingIgnoresEarlierInserts(t *testing.T) {
+	ctx := app.WithTenant(context.Background(), "t1")
+	r := NewSubscriptionRepo()
+	for month := 1; month <= 6; month++ {
+		sub := &domain.Subscription{
+			ID: uuid.New(), UserID: uuid.New(), ServiceName: "svc", Price: 1,
+			Currency: "RUB", BillingPeriod: domain.BillingMonthly, StartDate: date(2025, time.Month(month), 1),
+		}
+		if err := r.Create(ctx, sub); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	// Rows that sort before the cursor appear while paging; an offset
+	// would repeat rows, a keyset must not.
+	got := listAll(t, ctx, r, 2, func() {
+		sub := &domain.Subscription{
+			ID: uuid.New(), UserID: uuid.New(), ServiceName: "new", Price: 1,
+			Currency: "RUB", BillingPeriod: domain.BillingMonthly, StartDate: date(2026, 1, 1),
+		}
+		if err := r.Create(ctx, sub); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	})
+
+	seen := make(map[uuid.UUID]bool, len(got))
+	for _, id := range got {
+		if seen[id] {
+			t.Fatalf("row %s listed twice", id)
+		}
+		seen[id] = true
+	}
+	if len(got) != 6 {
+		t.Errorf("listed %d rows, want the 6 that existed before paging", len(got))
+	}
+}
+
+func TestListSkipsTrashAndOtherTenants(t *testing.T) {
+	ctx := app.WithTenant(context.Background(), "t1")
+	r := NewSubscriptionRepo()
+
+	newSub := func() *domain.Subscription {
+		return &domain.Subscription{
+			ID: uuid.New(), UserID: uuid.New(), ServiceName: "svc", Price: 1,
+			Currency: "RUB", BillingPeriod: domain.BillingMonthly, StartDate: date(2025, 1, 1),
+		}
+	}
+	kept, trashed, foreign := newSub(), newSub(), newSub()
+	for _, sub := range []*domain.Subscription{kept, trashed} {
+		if err := r.Create(ctx, sub); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if err := r.Create(app.WithTenant(context.Background(), "t2"), foreign); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := r.Delete(ctx, trashed.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	page, err := r.List(ctx, nil, nil, nil, 10)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != kept.ID {
+		t.Errorf("List = %d rows, want only %s", len(page), kept.ID)
+	}
+	if _, err := r.GetByID(ctx, foreign.ID); err != app.ErrNotFound {
+		t.Errorf("GetByID of another tenant's row: err = %v, want ErrNotFound", err)
+	}
+}
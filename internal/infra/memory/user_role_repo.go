@@ -0,0 +1,24 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// userRoleRepo assigns no roles: with the memory driver, roles come from
+// token claims only and everyone else is a member.
+type userRoleRepo struct{}
+
+func NewUserRoleRepo() app.UserRoleRepository {
+	return userRoleRepo{}
+}
+
+func (userRoleRepo) GetUserRole(ctx context.Context, userID uuid.UUID) (domain.Role, error) {
+	if _, err := app.TenantFromContext(ctx); err != nil {
+		return "", err
+	}
+	return "", app.ErrRoleNotAssigned
+}
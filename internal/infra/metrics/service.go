@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Outcomes of a service call, from the error it returned.
+const (
+	outcomeOK       = "ok"
+	outcomeInvalid  = "invalid"
+	outcomeNotFound = "not_found"
+	outcomeUnauth   = "unauthenticated"
+	outcomeDenied   = "forbidden"
+	outcomeConflict = "conflict"
+	outcomeCanceled = "canceled"
+	outcomeError    = "error"
+)
+
+// outcome classifies err; anything unrecognised is an error.
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return outcomeOK
+	case errors.Is(err, app.ErrInvalidInput), errors.Is(err, app.ErrIdempotencyKeyReused):
+		return outcomeInvalid
+	case errors.Is(err, app.ErrNotFound):
+		return outcomeNotFound
+	case errors.Is(err, app.ErrUnauthenticated):
+		return outcomeUnauth
+	case errors.Is(err, app.ErrForbidden):
+		return outcomeDenied
+	case errors.Is(err, app.ErrConflict):
+		return outcomeConflict
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return outcomeCanceled
+	}
+	return outcomeError
+}
+
+type service struct {
+	next    app.SubscriptionService
+	metrics *Metrics
+}
+
+// InstrumentService counts the outcome of every call made to next.
+func (m *Metrics) InstrumentService(next app.SubscriptionService) app.SubscriptionService {
+	return &service{next: next, metrics: m}
+}
+
+func (s *service) observe(method string, err error) {
+	s.metrics.calls.WithLabelValues(method, outcome(err)).Inc()
+}
+
+func (s *service) Create(ctx context.Context, input appdto.CreateInput) (*domain.Subscription, error) {
+	sub, err := s.next.Create(ctx, input)
+	s.observe("Create", err)
+	return sub, err
+}
+
+func (s *service) CreateIdempotent(ctx context.Context, key string, input appdto.CreateInput) (*domain.Subscription, bool, error) {
+	sub, replayed, err := s.next.CreateIdempotent(ctx, key, input)
+	s.observe("CreateIdempotent", err)
+	return sub, replayed, err
+}
+
+func (s *service) Import(ctx context.Context, inputs []appdto.CreateInput, atomic bool) ([]appdto.ImportResult, error) {
+	results, err := s.next.Import(ctx, inputs, atomic)
+	s.observe("Import", err)
+	return results, err
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	sub, err := s.next.Get(ctx, id)
+	s.observe("Get", err)
+	return sub, err
+}
+
+func (s *service) List(ctx context.Context, filter appdto.ListFilter) (*appdto.ListPage, error) {
+	page, err := s.next.List(ctx, filter)
+	s.observe("List", err)
+	return page, err
+}
+
+func (s *service) Export(ctx context.Context, filter appdto.ExportFilter, fn func(*domain.Subscription) error) error {
+	err := s.next.Export(ctx, filter, fn)
+	s.observe("Export", err)
+	return err
+}
+
+func (s *service) Update(ctx context.Context, id uuid.UUID, input appdto.UpdateInput) (*domain.Subscription, error) {
+	sub, err := s.next.Update(ctx, id, input)
+	s.observe("Update", err)
+	return sub, err
+}
+
+func (s *service) Delete(ctx context.Context, id uuid.UUID) error {
+	err := s.next.Delete(ctx, id)
+	s.observe("Delete", err)
+	return err
+}
+
+func (s *service) Restore(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	sub, err := s.next.Restore(ctx, id)
+	s.observe("Restore", err)
+	return sub, err
+}
+
+func (s *service) History(ctx context.Context, id uuid.UUID) ([]*domain.SubscriptionEvent, error) {
+	events, err := s.next.History(ctx, id)
+	s.observe("History", err)
+	return events, err
+}
+
+func (s *service) ListDeleted(ctx context.Context, userID *uuid.UUID, limit int32) ([]*domain.Subscription, error) {
+	subs, err := s.next.ListDeleted(ctx, userID, limit)
+	s.observe("ListDeleted", err)
+	return subs, err
+}
+
+func (s *service) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	n, err := s.next.PurgeDeleted(ctx, retention)
+	s.observe("PurgeDeleted", err)
+	return n, err
+}
+
+func (s *service) PurgeIdempotencyKeys(ctx context.Context, ttl time.Duration) (int64, error) {
+	n, err := s.next.PurgeIdempotencyKeys(ctx, ttl)
+	s.observe("PurgeIdempotencyKeys", err)
+	return n, err
+}
+
+func (s *service) Aggregate(ctx context.Context, filter appdto.AggregationFilter) (int64, error) {
+	total, err := s.next.Aggregate(ctx, filter)
+	s.observe("Aggregate", err)
+	return total, err
+}
+
+func (s *service) AggregateGrouped(ctx context.Context, filter appdto.AggregationFilter) ([]*appdto.CostBucket, error) {
+	buckets, err := s.next.AggregateGrouped(ctx, filter)
+	s.observe("AggregateGrouped", err)
+	return buckets, err
+}
+
+func (s *service) TimeSeries(ctx context.Context, filter appdto.AggregationFilter) ([]*appdto.MonthlyCost, error) {
+	series, err := s.next.TimeSeries(ctx, filter)
+	s.observe("TimeSeries", err)
+	return series, err
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	queries "github.com/Neroframe/sub_crudl/internal/infra/postgres/queries/generated"
+	"github.com/google/uuid"
+)
+
+type apiKeyRepo struct {
+	db *sql.DB
+	q  *queries.Queries
+}
+
+func NewAPIKeyRepo(db *sql.DB) app.APIKeyRepository {
+	return &apiKeyRepo{db: db, q: queries.New(db)}
+}
+
+func (r *apiKeyRepo) CreateAPIKey(ctx context.Context, key *domain.APIKey, hash string) error {
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+	return inTenantTx(ctx, r.db, r.q, func(q *queries.Queries, _ string) error {
+		return q.CreateAPIKey(ctx, queries.CreateAPIKeyParams{
+			ID:        key.ID,
+			TenantID:  key.TenantID,
+			Name:      key.Name,
+			Prefix:    key.Prefix,
+			KeyHash:   hash,
+			Scopes:    scopes,
+			ExpiresAt: toNullTime(key.ExpiresAt),
+			CreatedAt: key.CreatedAt,
+		})
+	})
+}
+
+func (r *apiKeyRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	// The caller has no tenant yet: the key is what tells which one it is in
+	var row queries.ApiKey
+	err := inAllTenantsTx(ctx, r.db, r.q, func(q *queries.Queries) error {
+		var err error
+		row, err = q.GetAPIKeyByHash(ctx, hash)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, app.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toDomainAPIKey(row), nil
+}
+
+func (r *apiKeyRepo) ListAPIKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	var rows []queries.ApiKey
+	err := inTenantTx(ctx, r.db, r.q, func(q *queries.Queries, tenant string) error {
+		var err error
+		rows, err = q.ListAPIKeys(ctx, tenant)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*domain.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, toDomainAPIKey(row))
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepo) RevokeAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error {
+	var n int64
+	err := inTenantTx(ctx, r.db, r.q, func(q *queries.Queries, tenant string) error {
+		var err error
+		n, err = q.RevokeAPIKey(ctx, queries.RevokeAPIKeyParams{
+			TenantID:  tenant,
+			ID:        id,
+			RevokedAt: toNullTime(&at),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return app.ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed migration/*.up.sql
+var migrations embed.FS
+
+// SchemaVersion returns the version of the newest migration shipped with the
+// binary, which the database must be at for the queries to work.
+func SchemaVersion() (uint, error) {
+	names, err := fs.Glob(migrations, "migration/*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(strings.TrimPrefix(name, "migration/"), "_")
+		v, err := strconv.ParseUint(prefix, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has no version prefix", name)
+		}
+		latest = max(latest, uint(v))
+	}
+	return latest, nil
+}
+
+// ReadinessCheck returns a check that the database answers and that its
+// schema, as recorded by golang-migrate, is at want and not left dirty by a
+// failed migration.
+func ReadinessCheck(db *sql.DB, want uint) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := db.PingContext(ctx); err != nil {
+			return fmt.Errorf("ping: %w", err)
+		}
+
+		var version uint
+		var dirty bool
+		err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("no migrations applied")
+		}
+		if err != nil {
+			return fmt.Errorf("read schema version: %w", err)
+		}
+		switch {
+		case dirty:
+			return fmt.Errorf("migration %d failed and left the schema dirty", version)
+		case version != want:
+			return fmt.Errorf("schema is at version %d, want %d", version, want)
+		}
+		return nil
+	}
+}
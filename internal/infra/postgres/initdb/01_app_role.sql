@@ -0,0 +1,4 @@
+-- Run by the postgres image on an empty data directory, before migrations.
+-- The API logs in as this role so that row-level security applies to it;
+-- migration 012 grants it access to the tables.
+CREATE ROLE subscriptions_app LOGIN PASSWORD 'subscriptions_app';
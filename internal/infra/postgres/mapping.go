@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	queries "github.com/Neroframe/sub_crudl/internal/infra/postgres/queries/generated"
+	"github.com/google/uuid"
+)
+
+func toDomain(row queries.Subscription) *domain.Subscription {
+	return &domain.Subscription{
+		ID:            row.ID,
+		ServiceName:   row.ServiceName,
+		UserID:        row.UserID,
+		StartDate:     row.StartDate,
+		EndDate:       fromNullTime(row.EndDate),
+		Price:         row.Price,
+		Currency:      row.Currency,
+		BillingPeriod: domain.BillingPeriod(row.BillingPeriod),
+		DeletedAt:     fromNullTime(row.DeletedAt),
+		Version:       row.Version,
+	}
+}
+
+func toDomainList(rows []queries.Subscription) []*domain.Subscription {
+	subs := make([]*domain.Subscription, 0, len(rows))
+	for _, row := range rows {
+		subs = append(subs, toDomain(row))
+	}
+	return subs
+}
+
+func toEventParams(event *domain.SubscriptionEvent) (queries.CreateSubscriptionEventParams, error) {
+	before, err := json.Marshal(event.Before)
+	if err != nil {
+		return queries.CreateSubscriptionEventParams{}, fmt.Errorf("failed to encode event snapshot: %w", err)
+	}
+	after, err := json.Marshal(event.After)
+	if err != nil {
+		return queries.CreateSubscriptionEventParams{}, fmt.Errorf("failed to encode event snapshot: %w", err)
+	}
+	return queries.CreateSubscriptionEventParams{
+		ID:             event.ID,
+		SubscriptionID: event.SubscriptionID,
+		Action:         string(event.Action),
+		Actor:          event.Actor,
+		Before:         before,
+		After:          after,
+		CreatedAt:      event.CreatedAt,
+	}, nil
+}
+
+func toDomainEvent(row queries.SubscriptionEvent) (*domain.SubscriptionEvent, error) {
+	event := &domain.SubscriptionEvent{
+		ID:             row.ID,
+		SubscriptionID: row.SubscriptionID,
+		Action:         domain.EventAction(row.Action),
+		Actor:          row.Actor,
+		CreatedAt:      row.CreatedAt,
+	}
+	if err := json.Unmarshal(row.Before, &event.Before); err != nil {
+		return nil, fmt.Errorf("failed to decode event %s snapshot: %w", row.ID, err)
+	}
+	if err := json.Unmarshal(row.After, &event.After); err != nil {
+		return nil, fmt.Errorf("failed to decode event %s snapshot: %w", row.ID, err)
+	}
+	return event, nil
+}
+
+func toIdempotencyRecord(row queries.IdempotencyKey) (*appdto.IdempotencyRecord, error) {
+	rec := &appdto.IdempotencyRecord{
+		Key:         row.Key,
+		RequestHash: row.RequestHash,
+		CreatedAt:   row.CreatedAt,
+	}
+	if err := json.Unmarshal(row.Response, &rec.Response); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotent response for key %q: %w", row.Key, err)
+	}
+	return rec, nil
+}
+
+func toDomainAPIKey(row queries.ApiKey) *domain.APIKey {
+	scopes := make([]domain.APIKeyScope, len(row.Scopes))
+	for i, scope := range row.Scopes {
+		scopes[i] = domain.APIKeyScope(scope)
+	}
+	return &domain.APIKey{
+		ID:        row.ID,
+		TenantID:  row.TenantID,
+		Name:      row.Name,
+		Prefix:    row.Prefix,
+		Scopes:    scopes,
+		ExpiresAt: fromNullTime(row.ExpiresAt),
+		CreatedAt: row.CreatedAt,
+		RevokedAt: fromNullTime(row.RevokedAt),
+	}
+}
+
+func toCostBucket(row queries.AggregateCostGroupedRow) appdto.CurrencyBucket {
+	b := appdto.CurrencyBucket{Currency: row.Currency}
+	b.Total = row.Total
+	b.Subscriptions = row.Subscriptions
+	if row.ServiceName.Valid {
+		b.ServiceName = &row.ServiceName.String
+	}
+	if row.UserID.Valid {
+		b.UserID = &row.UserID.UUID
+	}
+	if row.Month.Valid {
+		b.Month = &row.Month.Time
+	}
+	return b
+}
+
+// Nil filters and optional columns map to SQL NULL; the queries skip NULL filters.
+
+func toNullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+func toNullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func fromNullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions
+  ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
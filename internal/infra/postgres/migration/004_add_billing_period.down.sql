@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS subscription_month_charge(INTEGER, TEXT, DATE, DATE, BOOLEAN);
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS billing_period;
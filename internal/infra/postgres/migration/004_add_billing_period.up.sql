@@ -0,0 +1,33 @@
+ALTER TABLE subscriptions
+  ADD COLUMN billing_period TEXT NOT NULL DEFAULT 'monthly'
+    CHECK (billing_period IN ('weekly', 'monthly', 'quarterly', 'yearly'));
+
+-- Amount a subscription bills in the month starting at p_month
+CREATE OR REPLACE FUNCTION subscription_month_charge(
+  p_price INTEGER,
+  p_billing_period TEXT,
+  p_start_date DATE,
+  p_month DATE,
+  p_normalize BOOLEAN
+) RETURNS NUMERIC
+LANGUAGE sql IMMUTABLE AS $$
+  SELECT p_price::numeric * CASE
+    -- Monthly-equivalent price
+    WHEN p_normalize THEN CASE p_billing_period
+      WHEN 'weekly' THEN 52.0 / 12
+      WHEN 'quarterly' THEN 1.0 / 3
+      WHEN 'yearly' THEN 1.0 / 12
+      ELSE 1
+    END
+    -- Number of renewals (counted from p_start_date) falling in p_month
+    ELSE CASE p_billing_period
+      WHEN 'weekly' THEN
+        ((p_month + interval '1 month')::date - p_start_date + 6) / 7
+        - (GREATEST(p_month, p_start_date) - p_start_date + 6) / 7
+      WHEN 'quarterly' THEN CASE WHEN ((EXTRACT(YEAR FROM p_month) - EXTRACT(YEAR FROM p_start_date)) * 12
+        + EXTRACT(MONTH FROM p_month) - EXTRACT(MONTH FROM p_start_date))::int % 3 = 0 THEN 1 ELSE 0 END
+      WHEN 'yearly' THEN CASE WHEN EXTRACT(MONTH FROM p_month) = EXTRACT(MONTH FROM p_start_date) THEN 1 ELSE 0 END
+      ELSE 1
+    END
+  END
+$$;
@@ -0,0 +1,5 @@
+ALTER TABLE subscriptions ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS subscriptions_deleted_at_idx
+  ON subscriptions (deleted_at)
+  WHERE deleted_at IS NOT NULL;
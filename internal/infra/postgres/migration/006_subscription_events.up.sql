@@ -0,0 +1,13 @@
+-- Audit trail; rows outlive purged subscriptions, so there is no foreign key.
+CREATE TABLE IF NOT EXISTS subscription_events (
+  id UUID PRIMARY KEY,
+  subscription_id UUID NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore')),
+  actor TEXT NOT NULL,
+  before JSONB NOT NULL DEFAULT 'null',
+  after JSONB NOT NULL DEFAULT 'null',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS subscription_events_subscription_id_idx
+  ON subscription_events (subscription_id, created_at);
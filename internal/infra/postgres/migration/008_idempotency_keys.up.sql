@@ -0,0 +1,11 @@
+-- Create requests remembered per Idempotency-Key, so a retry replays the
+-- original response instead of creating a duplicate.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  key TEXT PRIMARY KEY,
+  request_hash TEXT NOT NULL,
+  response JSONB NOT NULL DEFAULT 'null',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx
+  ON idempotency_keys (created_at);
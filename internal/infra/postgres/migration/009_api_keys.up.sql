@@ -0,0 +1,13 @@
+-- Keys of service-to-service callers. Only a SHA-256 hash of each secret is
+-- stored; prefix keeps enough of it to tell keys apart.
+CREATE TABLE IF NOT EXISTS api_keys (
+  id UUID PRIMARY KEY,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  key_hash TEXT NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL
+    CHECK (scopes <@ ARRAY['read', 'write', 'aggregate', 'admin']::TEXT[]),
+  expires_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at TIMESTAMPTZ
+);
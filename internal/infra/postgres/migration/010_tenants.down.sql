@@ -0,0 +1,30 @@
+DROP POLICY IF EXISTS tenant_isolation ON idempotency_keys;
+ALTER TABLE idempotency_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON subscription_events;
+ALTER TABLE subscription_events NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_events DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+ALTER TABLE subscriptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS api_keys_tenant_created_at_idx;
+
+DROP INDEX IF EXISTS subscription_events_tenant_subscription_id_idx;
+CREATE INDEX IF NOT EXISTS subscription_events_subscription_id_idx
+  ON subscription_events (subscription_id, created_at);
+
+DROP INDEX IF EXISTS subscriptions_tenant_start_date_id_idx;
+CREATE INDEX IF NOT EXISTS subscriptions_start_date_id_idx
+  ON subscriptions (start_date DESC, id DESC);
+
+-- Fails if two tenants used the same idempotency key; purge them first
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key);
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE subscription_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,49 @@
+-- Every subscription, and everything derived from one, belongs to a tenant.
+-- Rows that predate tenants go to 'default'; new rows must name theirs.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE subscriptions ALTER COLUMN tenant_id DROP DEFAULT;
+
+ALTER TABLE subscription_events ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE subscription_events ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- Idempotency keys are chosen by clients, so they are unique per tenant only
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE idempotency_keys ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (tenant_id, key);
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ALTER COLUMN tenant_id DROP DEFAULT;
+
+DROP INDEX IF EXISTS subscriptions_start_date_id_idx;
+CREATE INDEX IF NOT EXISTS subscriptions_tenant_start_date_id_idx
+  ON subscriptions (tenant_id, start_date DESC, id DESC);
+
+DROP INDEX IF EXISTS subscription_events_subscription_id_idx;
+CREATE INDEX IF NOT EXISTS subscription_events_tenant_subscription_id_idx
+  ON subscription_events (tenant_id, subscription_id, created_at);
+
+CREATE INDEX IF NOT EXISTS api_keys_tenant_created_at_idx
+  ON api_keys (tenant_id, created_at DESC);
+
+-- Row-level security backs up the tenant_id filters in the queries. The API
+-- sets app.tenant_id for each transaction; maintenance jobs that purge old
+-- rows set app.all_tenants instead. Policies do not bind superusers or roles
+-- with BYPASSRLS, so the API must connect with another role for them to apply.
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscriptions
+  USING (tenant_id = current_setting('app.tenant_id', true)
+         OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE subscription_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_events FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscription_events
+  USING (tenant_id = current_setting('app.tenant_id', true)
+         OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON idempotency_keys
+  USING (tenant_id = current_setting('app.tenant_id', true)
+         OR current_setting('app.all_tenants', true) = 'on');
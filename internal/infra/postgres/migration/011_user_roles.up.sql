@@ -0,0 +1,10 @@
+-- Roles assigned to users, for tokens that carry no role claim. Users
+-- without a row here are members.
+CREATE TABLE IF NOT EXISTS user_roles (
+  tenant_id TEXT NOT NULL,
+  user_id UUID NOT NULL,
+  role TEXT NOT NULL
+    CHECK (role IN ('admin', 'analyst', 'member')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tenant_id, user_id)
+);
@@ -0,0 +1,17 @@
+DROP POLICY IF EXISTS tenant_isolation ON user_roles;
+ALTER TABLE user_roles NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE user_roles DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+ALTER TABLE api_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE api_keys DISABLE ROW LEVEL SECURITY;
+
+-- The role itself stays: it may have been created outside the migrations and
+-- can still hold open connections.
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+  REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM subscriptions_app;
+REVOKE SELECT ON schema_migrations FROM subscriptions_app;
+REVOKE SELECT, INSERT, UPDATE, DELETE
+  ON subscriptions, subscription_events, idempotency_keys, api_keys, user_roles
+  FROM subscriptions_app;
+REVOKE USAGE ON SCHEMA public FROM subscriptions_app;
@@ -0,0 +1,36 @@
+-- The API connects as subscriptions_app. Superusers skip row-level security
+-- even where it is forced, so the role that runs migrations must not be the
+-- one that serves requests. docker-compose creates the role
+-- with a password before the first migration (see initdb/); anywhere else it
+-- is created here without LOGIN and an operator has to give it one.
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'subscriptions_app') THEN
+    CREATE ROLE subscriptions_app NOLOGIN;
+  END IF;
+END
+$$;
+
+GRANT USAGE ON SCHEMA public TO subscriptions_app;
+GRANT SELECT, INSERT, UPDATE, DELETE
+  ON subscriptions, subscription_events, idempotency_keys, api_keys, user_roles
+  TO subscriptions_app;
+-- Read by the readiness check
+GRANT SELECT ON schema_migrations TO subscriptions_app;
+-- Tables added by later migrations
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+  GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO subscriptions_app;
+
+-- API keys are looked up by hash before the tenant is known; that lookup
+-- sets app.all_tenants like the maintenance jobs do.
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON api_keys
+  USING (tenant_id = current_setting('app.tenant_id', true)
+         OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE user_roles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_roles FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_roles
+  USING (tenant_id = current_setting('app.tenant_id', true)
+         OR current_setting('app.all_tenants', true) = 'on');
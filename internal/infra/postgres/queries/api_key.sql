@@ -0,0 +1,14 @@
+-- name: CreateAPIKey :exec
+INSERT INTO api_keys (id, tenant_id, name, prefix, key_hash, scopes, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetAPIKeyByHash :one
+-- Searches all tenants: the key is what tells which tenant the caller is in.
+SELECT * FROM api_keys WHERE key_hash = $1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys WHERE tenant_id = $1 ORDER BY created_at DESC, id DESC;
+
+-- name: RevokeAPIKey :execrows
+-- Affects no rows when the key is unknown or already revoked.
+UPDATE api_keys SET revoked_at = $3 WHERE tenant_id = $1 AND id = $2 AND revoked_at IS NULL;
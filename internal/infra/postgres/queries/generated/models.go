@@ -6,16 +6,60 @@ package queries
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID        uuid.UUID
+	Name      string
+	Prefix    string
+	KeyHash   string
+	Scopes    []string
+	ExpiresAt sql.NullTime
+	CreatedAt time.Time
+	RevokedAt sql.NullTime
+	TenantID  string
+}
+
+type IdempotencyKey struct {
+	Key         string
+	RequestHash string
+	Response    json.RawMessage
+	CreatedAt   time.Time
+	TenantID    string
+}
+
 type Subscription struct {
-	ID          uuid.UUID
-	ServiceName string
-	Price       int32
-	UserID      uuid.UUID
-	StartDate   time.Time
-	EndDate     sql.NullTime
+	ID            uuid.UUID
+	ServiceName   string
+	Price         int32
+	UserID        uuid.UUID
+	StartDate     time.Time
+	EndDate       sql.NullTime
+	Currency      string
+	BillingPeriod string
+	DeletedAt     sql.NullTime
+	Version       int32
+	TenantID      string
+}
+
+type SubscriptionEvent struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+	Action         string
+	Actor          string
+	Before         json.RawMessage
+	After          json.RawMessage
+	CreatedAt      time.Time
+	TenantID       string
+}
+
+type UserRole struct {
+	TenantID  string
+	UserID    uuid.UUID
+	Role      string
+	CreatedAt time.Time
 }
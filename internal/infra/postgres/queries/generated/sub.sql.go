@@ -8,79 +8,244 @@ package queries
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-const aggregateCost = `-- name: AggregateCost :one
-SELECT COALESCE(SUM(price), 0) FROM subscriptions
-WHERE ($1::uuid IS NULL OR user_id = $1)
-  AND ($2::text IS NULL OR service_name = $2)
-  AND start_date <= $3
-  AND (end_date IS NULL OR end_date >= $4)
+const aggregateCost = `-- name: AggregateCost :many
+SELECT s.currency, ROUND(SUM(subscription_month_charge(s.price, s.billing_period, s.start_date, s.end_date, m.month::date, $1::boolean)))::bigint AS total
+FROM subscriptions s
+JOIN generate_series($2::date, $3::date, interval '1 month') AS m(month)
+  ON date_trunc('month', s.start_date) <= m.month
+ AND (s.end_date IS NULL OR s.end_date >= m.month)
+WHERE s.tenant_id = $4
+  AND s.deleted_at IS NULL
+  AND ($5::uuid IS NULL OR s.user_id = $5)
+  AND ($6::text IS NULL OR s.service_name = $6)
+GROUP BY s.currency
 `
 
 type AggregateCostParams struct {
-	Column1   uuid.UUID
-	Column2   string
-	StartDate time.Time
-	EndDate   sql.NullTime
+	Normalize   bool
+	StartPeriod time.Time
+	EndPeriod   time.Time
+	TenantID    string
+	UserID      uuid.NullUUID
+	ServiceName sql.NullString
 }
 
-func (q *Queries) AggregateCost(ctx context.Context, arg AggregateCostParams) (interface{}, error) {
-	row := q.db.QueryRowContext(ctx, aggregateCost,
-		arg.Column1,
-		arg.Column2,
-		arg.StartDate,
-		arg.EndDate,
+type AggregateCostRow struct {
+	Currency string
+	Total    int64
+}
+
+// Sums what each subscription bills in every month of the window it is active in
+// (or its monthly-equivalent price when normalize is set).
+// Totals are per currency; conversion happens in the application.
+func (q *Queries) AggregateCost(ctx context.Context, arg AggregateCostParams) ([]AggregateCostRow, error) {
+	rows, err := q.db.QueryContext(ctx, aggregateCost,
+		arg.Normalize,
+		arg.StartPeriod,
+		arg.EndPeriod,
+		arg.TenantID,
+		arg.UserID,
+		arg.ServiceName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AggregateCostRow
+	for rows.Next() {
+		var i AggregateCostRow
+		if err := rows.Scan(&i.Currency, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const aggregateCostGrouped = `-- name: AggregateCostGrouped :many
+SELECT
+  (CASE WHEN $1::boolean THEN s.service_name END)::text AS service_name,
+  (CASE WHEN $2::boolean THEN s.user_id END)::uuid AS user_id,
+  (CASE WHEN $3::boolean THEN m.month END)::date AS month,
+  s.currency,
+  ROUND(SUM(subscription_month_charge(s.price, s.billing_period, s.start_date, s.end_date, m.month::date, $4::boolean)))::bigint AS total,
+  COUNT(DISTINCT s.id)::bigint AS subscriptions
+FROM subscriptions s
+JOIN generate_series($5::date, $6::date, interval '1 month') AS m(month)
+  ON date_trunc('month', s.start_date) <= m.month
+ AND (s.end_date IS NULL OR s.end_date >= m.month)
+WHERE s.tenant_id = $7
+  AND s.deleted_at IS NULL
+  AND ($8::uuid IS NULL OR s.user_id = $8)
+  AND ($9::text IS NULL OR s.service_name = $9)
+GROUP BY 1, 2, 3, 4
+ORDER BY 1, 2, 3, 4
+`
+
+type AggregateCostGroupedParams struct {
+	ByServiceName bool
+	ByUserID      bool
+	ByMonth       bool
+	Normalize     bool
+	StartPeriod   time.Time
+	EndPeriod     time.Time
+	TenantID      string
+	UserID        uuid.NullUUID
+	ServiceName   sql.NullString
+}
+
+type AggregateCostGroupedRow struct {
+	ServiceName   sql.NullString
+	UserID        uuid.NullUUID
+	Month         sql.NullTime
+	Currency      string
+	Total         int64
+	Subscriptions int64
+}
+
+// Same billing rule as AggregateCost; columns not selected for grouping are NULL.
+func (q *Queries) AggregateCostGrouped(ctx context.Context, arg AggregateCostGroupedParams) ([]AggregateCostGroupedRow, error) {
+	rows, err := q.db.QueryContext(ctx, aggregateCostGrouped,
+		arg.ByServiceName,
+		arg.ByUserID,
+		arg.ByMonth,
+		arg.Normalize,
+		arg.StartPeriod,
+		arg.EndPeriod,
+		arg.TenantID,
+		arg.UserID,
+		arg.ServiceName,
 	)
-	var coalesce interface{}
-	err := row.Scan(&coalesce)
-	return coalesce, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AggregateCostGroupedRow
+	for rows.Next() {
+		var i AggregateCostGroupedRow
+		if err := rows.Scan(
+			&i.ServiceName,
+			&i.UserID,
+			&i.Month,
+			&i.Currency,
+			&i.Total,
+			&i.Subscriptions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const createSubscription = `-- name: CreateSubscription :exec
-INSERT INTO subscriptions (id, service_name, price, user_id, start_date, end_date)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO subscriptions (id, tenant_id, service_name, price, user_id, start_date, end_date, currency, billing_period)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 `
 
 type CreateSubscriptionParams struct {
-	ID          uuid.UUID
-	ServiceName string
-	Price       int32
-	UserID      uuid.UUID
-	StartDate   time.Time
-	EndDate     sql.NullTime
+	ID            uuid.UUID
+	TenantID      string
+	ServiceName   string
+	Price         int32
+	UserID        uuid.UUID
+	StartDate     time.Time
+	EndDate       sql.NullTime
+	Currency      string
+	BillingPeriod string
 }
 
 func (q *Queries) CreateSubscription(ctx context.Context, arg CreateSubscriptionParams) error {
 	_, err := q.db.ExecContext(ctx, createSubscription,
 		arg.ID,
+		arg.TenantID,
 		arg.ServiceName,
 		arg.Price,
 		arg.UserID,
 		arg.StartDate,
 		arg.EndDate,
+		arg.Currency,
+		arg.BillingPeriod,
 	)
 	return err
 }
 
-const deleteSubscription = `-- name: DeleteSubscription :exec
-DELETE FROM subscriptions WHERE id = $1
+const createSubscriptionEvent = `-- name: CreateSubscriptionEvent :exec
+INSERT INTO subscription_events (id, tenant_id, subscription_id, action, actor, before, after, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 `
 
-func (q *Queries) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteSubscription, id)
+type CreateSubscriptionEventParams struct {
+	ID             uuid.UUID
+	TenantID       string
+	SubscriptionID uuid.UUID
+	Action         string
+	Actor          string
+	Before         json.RawMessage
+	After          json.RawMessage
+	CreatedAt      time.Time
+}
+
+func (q *Queries) CreateSubscriptionEvent(ctx context.Context, arg CreateSubscriptionEventParams) error {
+	_, err := q.db.ExecContext(ctx, createSubscriptionEvent,
+		arg.ID,
+		arg.TenantID,
+		arg.SubscriptionID,
+		arg.Action,
+		arg.Actor,
+		arg.Before,
+		arg.After,
+		arg.CreatedAt,
+	)
 	return err
 }
 
+const deleteSubscription = `-- name: DeleteSubscription :execrows
+UPDATE subscriptions SET deleted_at = now() WHERE tenant_id = $1 AND id = $2 AND deleted_at IS NULL
+`
+
+type DeleteSubscriptionParams struct {
+	TenantID string
+	ID       uuid.UUID
+}
+
+func (q *Queries) DeleteSubscription(ctx context.Context, arg DeleteSubscriptionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSubscription, arg.TenantID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getSubscriptionByID = `-- name: GetSubscriptionByID :one
-SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions WHERE id = $1
+SELECT id, service_name, price, user_id, start_date, end_date, currency, billing_period, deleted_at, version, tenant_id FROM subscriptions WHERE tenant_id = $1 AND id = $2 AND deleted_at IS NULL
 `
 
-func (q *Queries) GetSubscriptionByID(ctx context.Context, id uuid.UUID) (Subscription, error) {
-	row := q.db.QueryRowContext(ctx, getSubscriptionByID, id)
+type GetSubscriptionByIDParams struct {
+	TenantID string
+	ID       uuid.UUID
+}
+
+func (q *Queries) GetSubscriptionByID(ctx context.Context, arg GetSubscriptionByIDParams) (Subscription, error) {
+	row := q.db.QueryRowContext(ctx, getSubscriptionByID, arg.TenantID, arg.ID)
 	var i Subscription
 	err := row.Scan(
 		&i.ID,
@@ -89,32 +254,139 @@ func (q *Queries) GetSubscriptionByID(ctx context.Context, id uuid.UUID) (Subscr
 		&i.UserID,
 		&i.StartDate,
 		&i.EndDate,
+		&i.Currency,
+		&i.BillingPeriod,
+		&i.DeletedAt,
+		&i.Version,
+		&i.TenantID,
 	)
 	return i, err
 }
 
+const listDeletedSubscriptions = `-- name: ListDeletedSubscriptions :many
+SELECT id, service_name, price, user_id, start_date, end_date, currency, billing_period, deleted_at, version, tenant_id
+FROM subscriptions
+WHERE tenant_id = $1
+  AND deleted_at IS NOT NULL
+  AND ($2::uuid IS NULL OR user_id = $2)
+ORDER BY deleted_at DESC, id DESC
+LIMIT $3
+`
+
+type ListDeletedSubscriptionsParams struct {
+	TenantID string
+	UserID   uuid.NullUUID
+	Limit    int32
+}
+
+func (q *Queries) ListDeletedSubscriptions(ctx context.Context, arg ListDeletedSubscriptionsParams) ([]Subscription, error) {
+	rows, err := q.db.QueryContext(ctx, listDeletedSubscriptions, arg.TenantID, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscription
+	for rows.Next() {
+		var i Subscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceName,
+			&i.Price,
+			&i.UserID,
+			&i.StartDate,
+			&i.EndDate,
+			&i.Currency,
+			&i.BillingPeriod,
+			&i.DeletedAt,
+			&i.Version,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptionEvents = `-- name: ListSubscriptionEvents :many
+SELECT id, subscription_id, action, actor, before, after, created_at, tenant_id FROM subscription_events
+WHERE tenant_id = $1 AND subscription_id = $2
+ORDER BY created_at, id
+`
+
+type ListSubscriptionEventsParams struct {
+	TenantID       string
+	SubscriptionID uuid.UUID
+}
+
+func (q *Queries) ListSubscriptionEvents(ctx context.Context, arg ListSubscriptionEventsParams) ([]SubscriptionEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listSubscriptionEvents, arg.TenantID, arg.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubscriptionEvent
+	for rows.Next() {
+		var i SubscriptionEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.Action,
+			&i.Actor,
+			&i.Before,
+			&i.After,
+			&i.CreatedAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSubscriptionsPaginated = `-- name: ListSubscriptionsPaginated :many
-SELECT id, service_name, price, user_id, start_date, end_date
+SELECT id, service_name, price, user_id, start_date, end_date, currency, billing_period, deleted_at, version, tenant_id
 FROM subscriptions
-WHERE ($1::uuid IS NULL OR user_id = $1)
-  AND ($2::text IS NULL OR service_name ILIKE '%' || $2 || '%')
-ORDER BY start_date DESC
-LIMIT $3 OFFSET $4
+WHERE tenant_id = $1
+  AND deleted_at IS NULL
+  AND ($2::uuid IS NULL OR user_id = $2)
+  AND ($3::text IS NULL OR service_name ILIKE '%' || $3 || '%')
+  AND ($4::date IS NULL
+       OR (start_date, id) < ($4::date, $5::uuid))
+ORDER BY start_date DESC, id DESC
+LIMIT $6
 `
 
 type ListSubscriptionsPaginatedParams struct {
-	Column1 uuid.UUID
-	Column2 string
-	Limit   int32
-	Offset  int32
+	TenantID       string
+	UserID         uuid.NullUUID
+	ServiceName    sql.NullString
+	AfterStartDate sql.NullTime
+	AfterID        uuid.NullUUID
+	Limit          int32
 }
 
 func (q *Queries) ListSubscriptionsPaginated(ctx context.Context, arg ListSubscriptionsPaginatedParams) ([]Subscription, error) {
 	rows, err := q.db.QueryContext(ctx, listSubscriptionsPaginated,
-		arg.Column1,
-		arg.Column2,
+		arg.TenantID,
+		arg.UserID,
+		arg.ServiceName,
+		arg.AfterStartDate,
+		arg.AfterID,
 		arg.Limit,
-		arg.Offset,
 	)
 	if err != nil {
 		return nil, err
@@ -130,6 +402,11 @@ func (q *Queries) ListSubscriptionsPaginated(ctx context.Context, arg ListSubscr
 			&i.UserID,
 			&i.StartDate,
 			&i.EndDate,
+			&i.Currency,
+			&i.BillingPeriod,
+			&i.DeletedAt,
+			&i.Version,
+			&i.TenantID,
 		); err != nil {
 			return nil, err
 		}
@@ -144,27 +421,70 @@ func (q *Queries) ListSubscriptionsPaginated(ctx context.Context, arg ListSubscr
 	return items, nil
 }
 
-const updateSubscription = `-- name: UpdateSubscription :exec
+const purgeDeletedSubscriptions = `-- name: PurgeDeletedSubscriptions :execrows
+DELETE FROM subscriptions WHERE deleted_at IS NOT NULL AND deleted_at < $1
+`
+
+// Maintenance job: runs across all tenants.
+func (q *Queries) PurgeDeletedSubscriptions(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedSubscriptions, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreSubscription = `-- name: RestoreSubscription :execrows
+UPDATE subscriptions SET deleted_at = NULL WHERE tenant_id = $1 AND id = $2 AND deleted_at IS NOT NULL
+`
+
+type RestoreSubscriptionParams struct {
+	TenantID string
+	ID       uuid.UUID
+}
+
+func (q *Queries) RestoreSubscription(ctx context.Context, arg RestoreSubscriptionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreSubscription, arg.TenantID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateSubscription = `-- name: UpdateSubscription :execrows
 UPDATE subscriptions
-SET service_name = $2, price = $3, start_date = $4, end_date = $5
-WHERE id = $1
+SET service_name = $2, price = $3, start_date = $4, end_date = $5, currency = $6, billing_period = $7,
+    version = version + 1
+WHERE tenant_id = $9 AND id = $1 AND deleted_at IS NULL AND version = $8
 `
 
 type UpdateSubscriptionParams struct {
-	ID          uuid.UUID
-	ServiceName string
-	Price       int32
-	StartDate   time.Time
-	EndDate     sql.NullTime
+	ID            uuid.UUID
+	ServiceName   string
+	Price         int32
+	StartDate     time.Time
+	EndDate       sql.NullTime
+	Currency      string
+	BillingPeriod string
+	Version       int32
+	TenantID      string
 }
 
-func (q *Queries) UpdateSubscription(ctx context.Context, arg UpdateSubscriptionParams) error {
-	_, err := q.db.ExecContext(ctx, updateSubscription,
+// Only applies when the row still has the version the caller read.
+func (q *Queries) UpdateSubscription(ctx context.Context, arg UpdateSubscriptionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateSubscription,
 		arg.ID,
 		arg.ServiceName,
 		arg.Price,
 		arg.StartDate,
 		arg.EndDate,
+		arg.Currency,
+		arg.BillingPeriod,
+		arg.Version,
+		arg.TenantID,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
@@ -0,0 +1,16 @@
+-- name: ClaimIdempotencyKey :execrows
+-- Affects no rows when the key is already taken. A concurrent claim of the
+-- same key blocks until the first transaction commits or rolls back.
+INSERT INTO idempotency_keys (tenant_id, key, request_hash)
+VALUES ($1, $2, $3)
+ON CONFLICT (tenant_id, key) DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys WHERE tenant_id = $1 AND key = $2;
+
+-- name: SetIdempotencyKeyResponse :exec
+UPDATE idempotency_keys SET response = $3 WHERE tenant_id = $1 AND key = $2;
+
+-- name: PurgeIdempotencyKeys :execrows
+-- Maintenance job: runs across all tenants.
+DELETE FROM idempotency_keys WHERE created_at < $1;
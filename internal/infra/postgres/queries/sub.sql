@@ -1,29 +1,88 @@
 -- name: CreateSubscription :exec
-INSERT INTO subscriptions (id, service_name, price, user_id, start_date, end_date)
-VALUES ($1, $2, $3, $4, $5, $6);
+INSERT INTO subscriptions (id, tenant_id, service_name, price, user_id, start_date, end_date, currency, billing_period)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
 
 -- name: GetSubscriptionByID :one
-SELECT * FROM subscriptions WHERE id = $1;
+SELECT * FROM subscriptions WHERE tenant_id = $1 AND id = $2 AND deleted_at IS NULL;
 
 -- name: ListSubscriptionsPaginated :many
 SELECT *
 FROM subscriptions
-WHERE ($1::uuid IS NULL OR user_id = $1)
-  AND ($2::text IS NULL OR service_name ILIKE '%' || $2 || '%')
-ORDER BY start_date DESC
-LIMIT $3 OFFSET $4;
+WHERE tenant_id = sqlc.arg('tenant_id')
+  AND deleted_at IS NULL
+  AND (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id'))
+  AND (sqlc.narg('service_name')::text IS NULL OR service_name ILIKE '%' || sqlc.narg('service_name') || '%')
+  AND (sqlc.narg('after_start_date')::date IS NULL
+       OR (start_date, id) < (sqlc.narg('after_start_date')::date, sqlc.narg('after_id')::uuid))
+ORDER BY start_date DESC, id DESC
+LIMIT sqlc.arg('limit');
 
--- name: UpdateSubscription :exec
+-- name: UpdateSubscription :execrows
+-- Only applies when the row still has the version the caller read.
 UPDATE subscriptions
-SET service_name = $2, price = $3, start_date = $4, end_date = $5
-WHERE id = $1;
-
--- name: DeleteSubscription :exec
-DELETE FROM subscriptions WHERE id = $1;
-
--- name: AggregateCost :one
-SELECT COALESCE(SUM(price), 0) FROM subscriptions
-WHERE ($1::uuid IS NULL OR user_id = $1)
-  AND ($2::text IS NULL OR service_name = $2)
-  AND start_date <= $3
-  AND (end_date IS NULL OR end_date >= $4);
+SET service_name = $2, price = $3, start_date = $4, end_date = $5, currency = $6, billing_period = $7,
+    version = version + 1
+WHERE tenant_id = $9 AND id = $1 AND deleted_at IS NULL AND version = $8;
+
+-- name: DeleteSubscription :execrows
+UPDATE subscriptions SET deleted_at = now() WHERE tenant_id = $1 AND id = $2 AND deleted_at IS NULL;
+
+-- name: RestoreSubscription :execrows
+UPDATE subscriptions SET deleted_at = NULL WHERE tenant_id = $1 AND id = $2 AND deleted_at IS NOT NULL;
+
+-- name: ListDeletedSubscriptions :many
+SELECT *
+FROM subscriptions
+WHERE tenant_id = sqlc.arg('tenant_id')
+  AND deleted_at IS NOT NULL
+  AND (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id'))
+ORDER BY deleted_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: PurgeDeletedSubscriptions :execrows
+-- Maintenance job: runs across all tenants.
+DELETE FROM subscriptions WHERE deleted_at IS NOT NULL AND deleted_at < $1;
+
+-- name: AggregateCost :many
+-- Sums what each subscription bills in every month of the window it is active in
+-- (or its monthly-equivalent price when normalize is set).
+-- Totals are per currency; conversion happens in the application.
+SELECT s.currency, ROUND(SUM(subscription_month_charge(s.price, s.billing_period, s.start_date, s.end_date, m.month::date, sqlc.arg('normalize')::boolean)))::bigint AS total
+FROM subscriptions s
+JOIN generate_series(sqlc.arg('start_period')::date, sqlc.arg('end_period')::date, interval '1 month') AS m(month)
+  ON date_trunc('month', s.start_date) <= m.month
+ AND (s.end_date IS NULL OR s.end_date >= m.month)
+WHERE s.tenant_id = sqlc.arg('tenant_id')
+  AND s.deleted_at IS NULL
+  AND (sqlc.narg('user_id')::uuid IS NULL OR s.user_id = sqlc.narg('user_id'))
+  AND (sqlc.narg('service_name')::text IS NULL OR s.service_name = sqlc.narg('service_name'))
+GROUP BY s.currency;
+
+-- name: AggregateCostGrouped :many
+-- Same billing rule as AggregateCost; columns not selected for grouping are NULL.
+SELECT
+  (CASE WHEN sqlc.arg('by_service_name')::boolean THEN s.service_name END)::text AS service_name,
+  (CASE WHEN sqlc.arg('by_user_id')::boolean THEN s.user_id END)::uuid AS user_id,
+  (CASE WHEN sqlc.arg('by_month')::boolean THEN m.month END)::date AS month,
+  s.currency,
+  ROUND(SUM(subscription_month_charge(s.price, s.billing_period, s.start_date, s.end_date, m.month::date, sqlc.arg('normalize')::boolean)))::bigint AS total,
+  COUNT(DISTINCT s.id)::bigint AS subscriptions
+FROM subscriptions s
+JOIN generate_series(sqlc.arg('start_period')::date, sqlc.arg('end_period')::date, interval '1 month') AS m(month)
+  ON date_trunc('month', s.start_date) <= m.month
+ AND (s.end_date IS NULL OR s.end_date >= m.month)
+WHERE s.tenant_id = sqlc.arg('tenant_id')
+  AND s.deleted_at IS NULL
+  AND (sqlc.narg('user_id')::uuid IS NULL OR s.user_id = sqlc.narg('user_id'))
+  AND (sqlc.narg('service_name')::text IS NULL OR s.service_name = sqlc.narg('service_name'))
+GROUP BY 1, 2, 3, 4
+ORDER BY 1, 2, 3, 4;
+
+-- name: CreateSubscriptionEvent :exec
+INSERT INTO subscription_events (id, tenant_id, subscription_id, action, actor, before, after, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListSubscriptionEvents :many
+SELECT * FROM subscription_events
+WHERE tenant_id = $1 AND subscription_id = $2
+ORDER BY created_at, id;
@@ -0,0 +1,9 @@
+-- name: SetCurrentTenant :exec
+-- Scopes the row-level security policies to one tenant for the rest of the
+-- transaction.
+SELECT set_config('app.tenant_id', sqlc.arg('tenant_id')::text, true);
+
+-- name: SetAllTenants :exec
+-- Lifts the row-level security policies for the rest of the transaction, for
+-- maintenance jobs.
+SELECT set_config('app.all_tenants', 'on', true);
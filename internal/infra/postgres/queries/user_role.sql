@@ -0,0 +1,2 @@
+-- name: GetUserRole :one
+SELECT role FROM user_roles WHERE tenant_id = $1 AND user_id = $2;
@@ -7,5 +7,144 @@ CREATE TABLE subscriptions (
   price INTEGER NOT NULL,
   user_id UUID NOT NULL,
   start_date DATE NOT NULL,
-  end_date DATE
+  end_date DATE,
+  currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$'),
+  billing_period TEXT NOT NULL DEFAULT 'monthly'
+    CHECK (billing_period IN ('weekly', 'monthly', 'quarterly', 'yearly')),
+  deleted_at TIMESTAMPTZ,
+  version INTEGER NOT NULL DEFAULT 1,
+  tenant_id TEXT NOT NULL
 );
+
+CREATE INDEX subscriptions_tenant_start_date_id_idx
+  ON subscriptions (tenant_id, start_date DESC, id DESC);
+
+CREATE INDEX subscriptions_deleted_at_idx
+  ON subscriptions (deleted_at)
+  WHERE deleted_at IS NOT NULL;
+
+-- Audit trail; rows outlive purged subscriptions, so there is no foreign key.
+CREATE TABLE subscription_events (
+  id UUID PRIMARY KEY,
+  subscription_id UUID NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore')),
+  actor TEXT NOT NULL,
+  before JSONB NOT NULL DEFAULT 'null',
+  after JSONB NOT NULL DEFAULT 'null',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  tenant_id TEXT NOT NULL
+);
+
+CREATE INDEX subscription_events_tenant_subscription_id_idx
+  ON subscription_events (tenant_id, subscription_id, created_at);
+
+-- Create requests remembered per Idempotency-Key, so a retry replays the
+-- original response instead of creating a duplicate. Keys are unique per
+-- tenant only.
+CREATE TABLE idempotency_keys (
+  key TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  response JSONB NOT NULL DEFAULT 'null',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  tenant_id TEXT NOT NULL,
+  PRIMARY KEY (tenant_id, key)
+);
+
+CREATE INDEX idempotency_keys_created_at_idx
+  ON idempotency_keys (created_at);
+
+-- Keys of service-to-service callers. Only a SHA-256 hash of each secret is
+-- stored; prefix keeps enough of it to tell keys apart.
+CREATE TABLE api_keys (
+  id UUID PRIMARY KEY,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  key_hash TEXT NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL
+    CHECK (scopes <@ ARRAY['read', 'write', 'aggregate', 'admin']::TEXT[]),
+  expires_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at TIMESTAMPTZ,
+  tenant_id TEXT NOT NULL
+);
+
+CREATE INDEX api_keys_tenant_created_at_idx
+  ON api_keys (tenant_id, created_at DESC);
+
+CREATE TABLE user_roles (
+  tenant_id TEXT NOT NULL,
+  user_id UUID NOT NULL,
+  role TEXT NOT NULL
+    CHECK (role IN ('admin', 'analyst', 'member')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tenant_id, user_id)
+);
+
+-- Row-level security backs up the tenant_id filters in the queries. The API
+-- sets app.tenant_id for each transaction; maintenance jobs that purge old
+-- rows set app.all_tenants instead. Policies do not bind superusers or roles
+-- with BYPASSRLS, so the API must connect with another role for them to apply.
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscriptions
+  USING (tenant_id = current_setting('app.tenant_id', true)
+         OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE subscription_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_events FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscription_events
+  USING (tenant_id = current_setting('app.tenant_id', true)
+         OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON idempotency_keys
+  USING (tenant_id = current_setting('app.tenant_id', true)
+         OR current_setting('app.all_tenants', true) = 'on');
+
+-- API keys are looked up by hash with app.all_tenants set, before the tenant
+-- is known
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON api_keys
+  USING (tenant_id = current_setting('app.tenant_id', true)
+         OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE user_roles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_roles FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_roles
+  USING (tenant_id = current_setting('app.tenant_id', true)
+         OR current_setting('app.all_tenants', true) = 'on');
+
+-- Amount a subscription bills in the month starting at p_month. Renewals
+-- after p_end_date, when set, are not billed.
+CREATE OR REPLACE FUNCTION subscription_month_charge(
+  p_price INTEGER,
+  p_billing_period TEXT,
+  p_start_date DATE,
+  p_end_date DATE,
+  p_month DATE,
+  p_normalize BOOLEAN
+) RETURNS NUMERIC
+LANGUAGE sql IMMUTABLE AS $$
+  SELECT p_price::numeric * CASE
+    -- Monthly-equivalent price
+    WHEN p_normalize THEN CASE p_billing_period
+      WHEN 'weekly' THEN 52.0 / 12
+      WHEN 'quarterly' THEN 1.0 / 3
+      WHEN 'yearly' THEN 1.0 / 12
+      ELSE 1
+    END
+    -- Number of renewals (counted from p_start_date) falling in p_month
+    ELSE CASE p_billing_period
+      -- LEAST skips the NULL end date of open-ended subscriptions
+      WHEN 'weekly' THEN
+        (LEAST((p_month + interval '1 month')::date, p_end_date + 1) - p_start_date + 6) / 7
+        - (GREATEST(p_month, p_start_date) - p_start_date + 6) / 7
+      WHEN 'quarterly' THEN CASE WHEN ((EXTRACT(YEAR FROM p_month) - EXTRACT(YEAR FROM p_start_date)) * 12
+        + EXTRACT(MONTH FROM p_month) - EXTRACT(MONTH FROM p_start_date))::int % 3 = 0 THEN 1 ELSE 0 END
+      WHEN 'yearly' THEN CASE WHEN EXTRACT(MONTH FROM p_month) = EXTRACT(MONTH FROM p_start_date) THEN 1 ELSE 0 END
+      ELSE 1
+    END
+  END
+$$;
@@ -3,57 +3,365 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/Neroframe/sub_crudl/internal/app"
-	generated "github.com/Neroframe/sub_crudl/internal/infra/postgres/queries/generated"
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
 	queries "github.com/Neroframe/sub_crudl/internal/infra/postgres/queries/generated"
 	"github.com/google/uuid"
 )
 
 type repo struct {
-	q *generated.Queries
+	db *sql.DB // nil when the repo is bound to a transaction
+	q  *queries.Queries
 }
 
 func NewSubscriptionRepo(db *sql.DB) app.SubscriptionRepository {
 	return &repo{
-		q: generated.New(db),
+		db: db,
+		q:  queries.New(db),
 	}
 }
 
-func (r *repo) Create(ctx context.Context, arg queries.CreateSubscriptionParams) error {
-	return r.q.CreateSubscription(ctx, arg)
+func (r *repo) WithTx(ctx context.Context, fn func(repo app.SubscriptionRepository) error) error {
+	// Already inside a transaction: join it
+	if r.db == nil {
+		return fn(r)
+	}
+
+	return inTenantTx(ctx, r.db, r.q, func(q *queries.Queries, _ string) error {
+		return fn(&repo{q: q})
+	})
 }
 
-func (r *repo) GetByID(ctx context.Context, id uuid.UUID) (queries.Subscription, error) {
-	return r.q.GetSubscriptionByID(ctx, id)
+// scoped runs fn for the tenant of ctx. Outside a transaction it opens one,
+// so the row-level security policies see the tenant as well as the queries.
+func (r *repo) scoped(ctx context.Context, fn func(q *queries.Queries, tenant string) error) error {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if r.db == nil {
+		return fn(r.q, tenant) // WithTx has set the tenant
+	}
+	return inTenantTx(ctx, r.db, r.q, fn)
 }
 
-func (r *repo) List(ctx context.Context, userID *uuid.UUID, serviceName *string) ([]queries.Subscription, error) {
-	// sqlc expects concrete values, not pointers
-	var uid uuid.UUID
-	var svc string
-	if userID != nil {
-		uid = *userID
+// allTenants runs a maintenance query with the row-level security policies
+// lifted.
+func (r *repo) allTenants(ctx context.Context, fn func(q *queries.Queries) error) error {
+	if r.db == nil {
+		if err := r.q.SetAllTenants(ctx); err != nil {
+			return err
+		}
+		return fn(r.q)
 	}
-	if serviceName != nil {
-		svc = *serviceName
+	return inAllTenantsTx(ctx, r.db, r.q, fn)
+}
+
+func (r *repo) Create(ctx context.Context, sub *domain.Subscription) error {
+	return r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		return q.CreateSubscription(ctx, queries.CreateSubscriptionParams{
+			ID:            sub.ID,
+			TenantID:      tenant,
+			ServiceName:   sub.ServiceName,
+			Price:         sub.Price,
+			UserID:        sub.UserID,
+			StartDate:     sub.StartDate,
+			EndDate:       toNullTime(sub.EndDate),
+			Currency:      sub.Currency,
+			BillingPeriod: string(sub.BillingPeriod),
+		})
+	})
+}
+
+func (r *repo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	var sub *domain.Subscription
+	err := r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		var err error
+		sub, err = getByID(ctx, q, tenant, id)
+		return err
+	})
+	return sub, err
+}
+
+func getByID(ctx context.Context, q *queries.Queries, tenant string, id uuid.UUID) (*domain.Subscription, error) {
+	row, err := q.GetSubscriptionByID(ctx, queries.GetSubscriptionByIDParams{TenantID: tenant, ID: id})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, app.ErrNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
+	return toDomain(row), nil
+}
+
+func (r *repo) List(ctx context.Context, userID *uuid.UUID, serviceName *string, after *appdto.Keyset, limit int32) ([]*domain.Subscription, error) {
 	params := queries.ListSubscriptionsPaginatedParams{
-		Column1: uid,
-		Column2: svc,
+		UserID:      toNullUUID(userID),
+		ServiceName: toNullString(serviceName),
+		Limit:       limit,
 	}
-	return r.q.ListSubscriptionsPaginated(ctx, params)
+	if after != nil {
+		params.AfterStartDate = sql.NullTime{Time: after.StartDate, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: after.ID, Valid: true}
+	}
+
+	var rows []queries.Subscription
+	err := r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		params.TenantID = tenant
+		var err error
+		rows, err = q.ListSubscriptionsPaginated(ctx, params)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toDomainList(rows), nil
 }
 
-func (r *repo) Update(ctx context.Context, arg queries.UpdateSubscriptionParams) error {
-	return r.q.UpdateSubscription(ctx, arg)
+func (r *repo) Update(ctx context.Context, sub *domain.Subscription, version int32) error {
+	return r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		n, err := q.UpdateSubscription(ctx, queries.UpdateSubscriptionParams{
+			ID:            sub.ID,
+			ServiceName:   sub.ServiceName,
+			Price:         sub.Price,
+			StartDate:     sub.StartDate,
+			EndDate:       toNullTime(sub.EndDate),
+			Currency:      sub.Currency,
+			BillingPeriod: string(sub.BillingPeriod),
+			Version:       version,
+			TenantID:      tenant,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// Either the row is gone or its version moved on
+			if _, err := getByID(ctx, q, tenant, sub.ID); err != nil {
+				return err
+			}
+			return app.ErrConflict
+		}
+		return nil
+	})
 }
 
+// Delete moves the subscription to the trash
 func (r *repo) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.q.DeleteSubscription(ctx, id)
+	return r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		n, err := q.DeleteSubscription(ctx, queries.DeleteSubscriptionParams{TenantID: tenant, ID: id})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return app.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// Restore takes a subscription out of the trash
+func (r *repo) Restore(ctx context.Context, id uuid.UUID) error {
+	return r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		n, err := q.RestoreSubscription(ctx, queries.RestoreSubscriptionParams{TenantID: tenant, ID: id})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return app.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *repo) ListDeleted(ctx context.Context, userID *uuid.UUID, limit int32) ([]*domain.Subscription, error) {
+	var rows []queries.Subscription
+	err := r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		var err error
+		rows, err = q.ListDeletedSubscriptions(ctx, queries.ListDeletedSubscriptionsParams{
+			TenantID: tenant,
+			UserID:   toNullUUID(userID),
+			Limit:    limit,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toDomainList(rows), nil
+}
+
+// PurgeDeleted permanently removes subscriptions of every tenant trashed
+// before the given time
+func (r *repo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := r.allTenants(ctx, func(q *queries.Queries) error {
+		var err error
+		n, err = q.PurgeDeletedSubscriptions(ctx, sql.NullTime{Time: before, Valid: true})
+		return err
+	})
+	return n, err
+}
+
+// CreateEvent appends an entry to the subscription audit trail
+func (r *repo) CreateEvent(ctx context.Context, event *domain.SubscriptionEvent) error {
+	params, err := toEventParams(event)
+	if err != nil {
+		return err
+	}
+	return r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		params.TenantID = tenant
+		return q.CreateSubscriptionEvent(ctx, params)
+	})
+}
+
+func (r *repo) ListEvents(ctx context.Context, subscriptionID uuid.UUID) ([]*domain.SubscriptionEvent, error) {
+	var rows []queries.SubscriptionEvent
+	err := r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		var err error
+		rows, err = q.ListSubscriptionEvents(ctx, queries.ListSubscriptionEventsParams{
+			TenantID:       tenant,
+			SubscriptionID: subscriptionID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*domain.SubscriptionEvent, 0, len(rows))
+	for _, row := range rows {
+		event, err := toDomainEvent(row)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// AggregateCost computes the total subscription cost per currency based on filters
+func (r *repo) AggregateCost(ctx context.Context, filter appdto.AggregationFilter) ([]appdto.CurrencyCost, error) {
+	var rows []queries.AggregateCostRow
+	err := r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		var err error
+		rows, err = q.AggregateCost(ctx, queries.AggregateCostParams{
+			Normalize:   filter.Normalize,
+			StartPeriod: filter.StartPeriod,
+			EndPeriod:   filter.EndPeriod,
+			TenantID:    tenant,
+			UserID:      toNullUUID(filter.UserID),
+			ServiceName: toNullString(filter.ServiceName),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	totals := make([]appdto.CurrencyCost, 0, len(rows))
+	for _, row := range rows {
+		totals = append(totals, appdto.CurrencyCost{Currency: row.Currency, Total: row.Total})
+	}
+	return totals, nil
+}
+
+// AggregateCostGrouped computes cost buckets for the requested grouping
+func (r *repo) AggregateCostGrouped(ctx context.Context, filter appdto.AggregationFilter) ([]appdto.CurrencyBucket, error) {
+	params := queries.AggregateCostGroupedParams{
+		Normalize:   filter.Normalize,
+		StartPeriod: filter.StartPeriod,
+		EndPeriod:   filter.EndPeriod,
+		UserID:      toNullUUID(filter.UserID),
+		ServiceName: toNullString(filter.ServiceName),
+	}
+	for _, field := range filter.GroupBy {
+		switch field {
+		case appdto.GroupByServiceName:
+			params.ByServiceName = true
+		case appdto.GroupByUserID:
+			params.ByUserID = true
+		case appdto.GroupByMonth:
+			params.ByMonth = true
+		}
+	}
+
+	var rows []queries.AggregateCostGroupedRow
+	err := r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		params.TenantID = tenant
+		var err error
+		rows, err = q.AggregateCostGrouped(ctx, params)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	buckets := make([]appdto.CurrencyBucket, 0, len(rows))
+	for _, row := range rows {
+		buckets = append(buckets, toCostBucket(row))
+	}
+	return buckets, nil
+}
+
+func (r *repo) ClaimIdempotencyKey(ctx context.Context, key, requestHash string) (bool, error) {
+	var n int64
+	err := r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		var err error
+		n, err = q.ClaimIdempotencyKey(ctx, queries.ClaimIdempotencyKeyParams{
+			TenantID:    tenant,
+			Key:         key,
+			RequestHash: requestHash,
+		})
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *repo) GetIdempotencyKey(ctx context.Context, key string) (*appdto.IdempotencyRecord, error) {
+	var row queries.IdempotencyKey
+	err := r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		var err error
+		row, err = q.GetIdempotencyKey(ctx, queries.GetIdempotencyKeyParams{TenantID: tenant, Key: key})
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, app.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toIdempotencyRecord(row)
+}
+
+// SaveIdempotentResponse stores the result to replay for a claimed key
+func (r *repo) SaveIdempotentResponse(ctx context.Context, key string, sub *domain.Subscription) error {
+	response, err := json.Marshal(sub)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotent response: %w", err)
+	}
+	return r.scoped(ctx, func(q *queries.Queries, tenant string) error {
+		return q.SetIdempotencyKeyResponse(ctx, queries.SetIdempotencyKeyResponseParams{
+			TenantID: tenant,
+			Key:      key,
+			Response: response,
+		})
+	})
 }
 
-// AggregateCost computes the total subscription cost based on filters
-func (r *repo) AggregateCost(ctx context.Context, arg queries.AggregateCostParams) (interface{}, error) {
-	return r.q.AggregateCost(ctx, arg)
+// PurgeIdempotencyKeys forgets idempotency keys of every tenant claimed
+// before the given time
+func (r *repo) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := r.allTenants(ctx, func(q *queries.Queries) error {
+		var err error
+		n, err = q.PurgeIdempotencyKeys(ctx, before)
+		return err
+	})
+	return n, err
 }
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	queries "github.com/Neroframe/sub_crudl/internal/infra/postgres/queries/generated"
+)
+
+// inTx runs fn in a new transaction, committing if it returns nil.
+func inTx(ctx context.Context, db *sql.DB, q *queries.Queries, fn func(q *queries.Queries) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op after Commit
+
+	if err := fn(q.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// inTenantTx runs fn in a new transaction for the tenant of ctx, so the
+// row-level security policies see the tenant as well as the queries.
+func inTenantTx(ctx context.Context, db *sql.DB, q *queries.Queries, fn func(q *queries.Queries, tenant string) error) error {
+	tenant, err := app.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return inTx(ctx, db, q, func(q *queries.Queries) error {
+		if err := q.SetCurrentTenant(ctx, tenant); err != nil {
+			return err
+		}
+		return fn(q, tenant)
+	})
+}
+
+// inAllTenantsTx runs fn in a new transaction with the row-level security
+// policies lifted.
+func inAllTenantsTx(ctx context.Context, db *sql.DB, q *queries.Queries, fn func(q *queries.Queries) error) error {
+	return inTx(ctx, db, q, func(q *queries.Queries) error {
+		if err := q.SetAllTenants(ctx); err != nil {
+			return err
+		}
+		return fn(q)
+	})
+}
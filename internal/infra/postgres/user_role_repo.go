@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	queries "github.com/Neroframe/sub_crudl/internal/infra/postgres/queries/generated"
+	"github.com/google/uuid"
+)
+
+type userRoleRepo struct {
+	db *sql.DB
+	q  *queries.Queries
+}
+
+func NewUserRoleRepo(db *sql.DB) app.UserRoleRepository {
+	return &userRoleRepo{db: db, q: queries.New(db)}
+}
+
+func (r *userRoleRepo) GetUserRole(ctx context.Context, userID uuid.UUID) (domain.Role, error) {
+	var role string
+	err := inTenantTx(ctx, r.db, r.q, func(q *queries.Queries, tenant string) error {
+		var err error
+		role, err = q.GetUserRole(ctx, queries.GetUserRoleParams{TenantID: tenant, UserID: userID})
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", app.ErrRoleNotAssigned
+	}
+	if err != nil {
+		return "", err
+	}
+	return domain.Role(role), nil
+}
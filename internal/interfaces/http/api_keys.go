@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/Neroframe/sub_crudl/internal/interfaces/http/dto"
+	"github.com/Neroframe/sub_crudl/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// APIKeyHandler serves the admin endpoints managing API keys.
+type APIKeyHandler struct {
+	KeyService app.APIKeyService
+	log        *logger.Logger
+}
+
+func NewAPIKeyHandler(keyService app.APIKeyService, logger *logger.Logger) *APIKeyHandler {
+	return &APIKeyHandler{KeyService: keyService, log: logger}
+}
+
+// IssueAPIKey godoc
+// @Summary     Issue an API key
+// @Description Create a key for a service-to-service caller. The secret is returned only in this response; send it as "Authorization: ApiKey <key>".
+// @Description Scopes: read (subscriptions, history, trash, export), write (create, import, change, delete, restore), aggregate (cost reports), admin (everything, including API keys).
+// @Tags        api-keys
+// @Accept      json
+// @Produce     json
+// @Param       key body     dto.IssueAPIKeyDTO true "Key name, scopes and optional expiry"
+// @Success     201 {object} dto.IssuedAPIKeyDTO
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     422 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /api-keys [post]
+func (h *APIKeyHandler) IssueAPIKey(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "IssueAPIKey")
+
+	var req dto.IssueAPIKeyDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeProblem(c, log, bindError(err), "Invalid request body")
+		return
+	}
+
+	input := appdto.IssueAPIKeyInput{Name: req.Name, ExpiresAt: req.ExpiresAt}
+	for _, scope := range req.Scopes {
+		input.Scopes = append(input.Scopes, domain.APIKeyScope(scope))
+	}
+
+	key, secret, err := h.KeyService.Issue(c.Request.Context(), input)
+	if err != nil {
+		writeProblem(c, log, err, "Failed to issue API key")
+		return
+	}
+
+	log.Info("api key issued", "id", key.ID, "name", key.Name)
+	c.JSON(http.StatusCreated, dto.IssuedAPIKeyDTO{APIKeyDTO: toAPIKeyDTO(key), Key: secret})
+}
+
+// ListAPIKeys godoc
+// @Summary     List API keys
+// @Description Get every API key, newest first, including revoked and expired ones. Secrets are never returned.
+// @Tags        api-keys
+// @Produce     json
+// @Success     200 {array}  dto.APIKeyDTO
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "ListAPIKeys")
+
+	keys, err := h.KeyService.List(c.Request.Context())
+	if err != nil {
+		writeProblem(c, log, err, "Failed to list API keys")
+		return
+	}
+
+	out := make([]dto.APIKeyDTO, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, toAPIKeyDTO(key))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// RevokeAPIKey godoc
+// @Summary     Revoke an API key
+// @Description Stop accepting a key. Revocation cannot be undone.
+// @Tags        api-keys
+// @Param       id  path     string true "API key ID"
+// @Success     204 {object} nil
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     404 {object} httpapi.Problem "Unknown or already revoked"
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "RevokeAPIKey")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeProblem(c, log, malformed("id", "id must be a UUID"), "Invalid API key ID")
+		return
+	}
+
+	if err := h.KeyService.Revoke(c.Request.Context(), id); err != nil {
+		writeProblem(c, log.With("id", id), err, "Failed to revoke API key")
+		return
+	}
+
+	log.Info("api key revoked", "id", id)
+	c.Status(http.StatusNoContent)
+}
+
+func toAPIKeyDTO(key *domain.APIKey) dto.APIKeyDTO {
+	out := dto.APIKeyDTO{
+		ID:        key.ID.String(),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    make([]string, len(key.Scopes)),
+		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+	}
+	for i, scope := range key.Scopes {
+		out.Scopes[i] = string(scope)
+	}
+	if key.ExpiresAt != nil {
+		s := key.ExpiresAt.Format(time.RFC3339)
+		out.ExpiresAt = &s
+	}
+	if key.RevokedAt != nil {
+		s := key.RevokedAt.Format(time.RFC3339)
+		out.RevokedAt = &s
+	}
+	return out
+}
@@ -0,0 +1,173 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/Neroframe/sub_crudl/pkg/jwt"
+	"github.com/Neroframe/sub_crudl/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TenantHeader names the tenant of a request. Credentials that carry a
+// tenant fix it, and the header may only repeat it; anonymous requests use
+// the header to pick theirs.
+const TenantHeader = "X-Tenant-ID"
+
+// Authorization schemes accepted by the Authenticator.
+const (
+	bearerScheme = "Bearer"
+	apiKeyScheme = "ApiKey"
+)
+
+// Authenticator resolves the caller and tenant of each request from its
+// bearer token or API key. Without a JWT verifier, requests that carry
+// neither act as an admin; main only allows that when authentication is
+// explicitly disabled for local development.
+type Authenticator struct {
+	jwt   *jwt.Verifier
+	keys  app.APIKeyService
+	roles app.RoleService
+	log   *logger.Logger
+}
+
+func NewAuthenticator(verifier *jwt.Verifier, keys app.APIKeyService, roles app.RoleService, logger *logger.Logger) *Authenticator {
+	return &Authenticator{jwt: verifier, keys: keys, roles: roles, log: logger}
+}
+
+// Middleware rejects requests without valid credentials and stores the
+// caller and tenant in the request context, replacing any X-Actor for the
+// audit trail.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logger.FromContext(c.Request.Context(), a.log).With("middleware", "Authenticator")
+
+		scheme, credentials, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		credentials = strings.TrimSpace(credentials)
+		switch {
+		case strings.EqualFold(scheme, apiKeyScheme) && credentials != "":
+			a.authenticateKey(c, log, credentials)
+		case a.jwt == nil:
+			a.next(c, log, app.Principal{Role: domain.RoleAdmin}, "", "")
+		case strings.EqualFold(scheme, bearerScheme) && credentials != "":
+			a.authenticateToken(c, log, credentials)
+		default:
+			a.reject(c, log, unauthorized("a bearer token or API key is required"))
+		}
+	}
+}
+
+func (a *Authenticator) authenticateToken(c *gin.Context, log *slog.Logger, token string) {
+	claims, err := a.jwt.Verify(token)
+	if err != nil {
+		log.Info("token rejected", "error", err)
+		a.reject(c, log, unauthorized("the bearer token is invalid or expired"))
+		return
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		a.reject(c, log, unauthorized("the token subject is not a user ID"))
+		return
+	}
+
+	tenant := claims.Tenant
+	if tenant == "" {
+		tenant = app.DefaultTenant
+	}
+	if !app.ValidTenantID(tenant) {
+		a.reject(c, log, unauthorized("the token tenant is not a valid tenant ID"))
+		return
+	}
+
+	// Roles assigned to the user are kept per tenant
+	role, err := a.roles.Resolve(app.WithTenant(c.Request.Context(), tenant), userID, claims.Roles)
+	if err != nil {
+		writeProblem(c, log, err, "Failed to resolve role")
+		c.Abort()
+		return
+	}
+
+	p := app.Principal{UserID: userID, Role: role}
+	a.next(c, log, p, tenant, userID.String())
+}
+
+func (a *Authenticator) authenticateKey(c *gin.Context, log *slog.Logger, secret string) {
+	key, err := a.keys.Authenticate(c.Request.Context(), secret)
+	if errors.Is(err, app.ErrInvalidAPIKey) {
+		a.reject(c, log, unauthorized(err.Error()))
+		return
+	}
+	if err != nil {
+		writeProblem(c, log, err, "Failed to check API key")
+		c.Abort()
+		return
+	}
+
+	// Services act for every user, within the scopes of their key
+	p := app.Principal{Role: domain.RoleAdmin, APIKey: key}
+	a.next(c, log, p, key.TenantID, "api-key:"+key.ID.String())
+}
+
+// next settles the tenant of the request and passes it on with the caller.
+// Credentials fix the tenant and the actor; anonymous requests, which have
+// neither, pick the tenant with the header and keep their X-Actor.
+func (a *Authenticator) next(c *gin.Context, log *slog.Logger, p app.Principal, tenant, actor string) {
+	header := c.GetHeader(TenantHeader)
+	if header != "" && !app.ValidTenantID(header) {
+		writeProblem(c, log, malformed(TenantHeader, TenantHeader+" must be 1 to 63 lowercase letters, digits, '-' or '_'"), "Invalid tenant")
+		c.Abort()
+		return
+	}
+	switch {
+	case tenant == "" && header != "":
+		tenant = header
+	case tenant == "":
+		tenant = app.DefaultTenant
+	}
+	if header != "" && header != tenant {
+		writeProblem(c, log, forbidden(TenantHeader+" does not match the tenant of the credentials"), "Forbidden")
+		c.Abort()
+		return
+	}
+
+	ctx := app.WithTenant(c.Request.Context(), tenant)
+	ctx = app.WithPrincipal(ctx, p)
+	if actor != "" {
+		ctx = app.WithActor(ctx, actor)
+	}
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+func (a *Authenticator) reject(c *gin.Context, log *slog.Logger, err error) {
+	if a.jwt != nil {
+		c.Writer.Header().Add("WWW-Authenticate", bearerScheme+` realm="subscriptions"`)
+	}
+	c.Writer.Header().Add("WWW-Authenticate", apiKeyScheme+` realm="subscriptions"`)
+	writeProblem(c, log, err, "Unauthorized")
+	c.Abort()
+}
+
+// Require rejects callers that do not hold scope. It runs after Middleware;
+// a request that reaches it without a caller is unauthenticated.
+func (a *Authenticator) Require(scope domain.APIKeyScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logger.FromContext(c.Request.Context(), a.log).With("middleware", "Require", "scope", scope)
+		p, ok := app.PrincipalFromContext(c.Request.Context())
+		if !ok {
+			a.reject(c, log, unauthorized("a bearer token or API key is required"))
+			return
+		}
+		if !p.Allows(scope) {
+			writeProblem(c, log, forbidden(fmt.Sprintf("this endpoint requires the %s scope", scope)), "Forbidden")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,25 +1,109 @@
 package dto
 
+import "time"
+
 type CreateSubscriptionDTO struct {
-	ServiceName string `json:"service_name" binding:"required"`
-	UserID      string `json:"user_id" binding:"required,uuid"`
-	StartDate   string `json:"start_date" binding:"required"` // format: MM-YYYY, validated manually
-	EndDate     string `json:"end_date,omitempty"`            // optional, same format
-	Price       int32  `json:"price" binding:"required,min=0"`
+	ServiceName   string `json:"service_name" binding:"required"`
+	UserID        string `json:"user_id,omitempty" binding:"omitempty,uuid"`                                         // defaults to the caller
+	StartDate     string `json:"start_date" binding:"required"`                                                      // format: MM-YYYY, validated manually
+	EndDate       string `json:"end_date,omitempty"`                                                                 // optional, same format
+	Price         *int32 `json:"price" binding:"required,min=0"`                                                     // pointer: an explicit 0 is a valid price
+	Currency      string `json:"currency,omitempty"`                                                                 // ISO 4217 in any case, checked by the service; defaults to RUB
+	BillingPeriod string `json:"billing_period,omitempty" binding:"omitempty,oneof=weekly monthly quarterly yearly"` // defaults to monthly
 }
 
+// UpdateSubscriptionDTO is a full replacement (PUT); omitted optional
+// fields are reset rather than kept.
 type UpdateSubscriptionDTO struct {
-	ServiceName *string `json:"service_name,omitempty"`
-	StartDate   *string `json:"start_date,omitempty"` // validated manually
-	EndDate     *string `json:"end_date,omitempty"`   // validated manually
-	Price       *int32  `json:"price,omitempty"`
+	ServiceName   string `json:"service_name" binding:"required"`
+	StartDate     string `json:"start_date" binding:"required"`                                                      // format: MM-YYYY, validated manually
+	EndDate       string `json:"end_date,omitempty"`                                                                 // omitted means open-ended
+	Price         *int32 `json:"price" binding:"required,min=0"`                                                     // pointer: an explicit 0 is a valid price
+	Currency      string `json:"currency,omitempty"`                                                                 // ISO 4217 in any case, checked by the service; defaults to RUB
+	BillingPeriod string `json:"billing_period,omitempty" binding:"omitempty,oneof=weekly monthly quarterly yearly"` // defaults to monthly
+}
+
+// PatchSubscriptionDTO documents the JSON Merge Patch body accepted by PATCH.
+// It is not bound directly: explicit nulls are read from the raw document.
+type PatchSubscriptionDTO struct {
+	ServiceName   *string `json:"service_name,omitempty"`
+	StartDate     *string `json:"start_date,omitempty"`                       // MM-YYYY
+	EndDate       *string `json:"end_date,omitempty" extensions:"x-nullable"` // MM-YYYY, null clears it
+	Price         *int32  `json:"price,omitempty"`
+	Currency      *string `json:"currency,omitempty"`
+	BillingPeriod *string `json:"billing_period,omitempty"`
 }
 
 type SubscriptionDTO struct {
-	ID          string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
-	ServiceName string  `json:"service_name" example:"Netflix"`
-	Price       int32   `json:"price" example:"999"`
-	UserID      string  `json:"user_id" example:"123e4567-e89b-12d3-a456-426614174000"`
-	StartDate   string  `json:"start_date" example:"01-2025"` // MM-YYYY
-	EndDate     *string `json:"end_date,omitempty" example:"12-2025"`
+	ID            string  `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ServiceName   string  `json:"service_name" example:"Netflix"`
+	Price         int32   `json:"price" example:"999"`
+	Currency      string  `json:"currency" example:"RUB"`
+	BillingPeriod string  `json:"billing_period" example:"monthly"`
+	UserID        string  `json:"user_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	StartDate     string  `json:"start_date" example:"01-2025"` // MM-YYYY
+	EndDate       *string `json:"end_date,omitempty" example:"12-2025"`
+	DeletedAt     *string `json:"deleted_at,omitempty" example:"2025-03-01T12:00:00Z"` // RFC 3339, only for trashed subscriptions
+	Version       int32   `json:"version" example:"1"`
+}
+
+type CostBucketDTO struct {
+	ServiceName   *string `json:"service_name,omitempty" example:"Netflix"`
+	UserID        *string `json:"user_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Month         *string `json:"month,omitempty" example:"01-2025"` // MM-YYYY
+	Total         int64   `json:"total" example:"1998"`
+	Subscriptions int64   `json:"subscriptions" example:"2"`
+}
+
+type MonthlyCostDTO struct {
+	Month string `json:"month" example:"01-2025"` // MM-YYYY
+	Total int64  `json:"total" example:"999"`
+}
+
+type SubscriptionEventDTO struct {
+	ID             string           `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	SubscriptionID string           `json:"subscription_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Action         string           `json:"action" example:"update"` // create, update, delete, restore
+	Actor          string           `json:"actor" example:"support@example.com"`
+	Before         *SubscriptionDTO `json:"before,omitempty"`
+	After          *SubscriptionDTO `json:"after,omitempty"`
+	CreatedAt      string           `json:"created_at" example:"2025-03-01T12:00:00Z"`
+}
+
+// ImportRowDTO reports the outcome of one imported row; exactly one of ID
+// and Error is set once the row has been processed.
+type ImportRowDTO struct {
+	Row   int    `json:"row" example:"1"`
+	ID    string `json:"id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Error string `json:"error,omitempty" example:"invalid input: end_date must not be before start_date"`
+}
+
+type IssueAPIKeyDTO struct {
+	Name      string     `json:"name" binding:"required" example:"billing-worker"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=read write aggregate admin" example:"read,aggregate"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2026-01-01T00:00:00Z"` // RFC 3339, omitted for a key that never expires
+}
+
+type APIKeyDTO struct {
+	ID        string   `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name      string   `json:"name" example:"billing-worker"`
+	Prefix    string   `json:"prefix" example:"sk_Xb3kQ9aZ"` // first characters of the secret
+	Scopes    []string `json:"scopes" example:"read,aggregate"`
+	ExpiresAt *string  `json:"expires_at,omitempty" example:"2026-01-01T00:00:00Z"`
+	CreatedAt string   `json:"created_at" example:"2025-03-01T12:00:00Z"`
+	RevokedAt *string  `json:"revoked_at,omitempty" example:"2025-06-01T12:00:00Z"`
+}
+
+// IssuedAPIKeyDTO is returned once, when a key is issued; Key is the secret
+// to send as "Authorization: ApiKey <key>" and cannot be retrieved later.
+type IssuedAPIKeyDTO struct {
+	APIKeyDTO
+	Key string `json:"key" example:"sk_Xb3kQ9aZ..."`
+}
+
+// HealthDTO is the body of the probes. Checks maps each dependency to "ok" or
+// "unavailable"; the reason is only logged, as the probes are unauthenticated.
+type HealthDTO struct {
+	Status string            `json:"status" example:"ok"`
+	Checks map[string]string `json:"checks,omitempty"`
 }
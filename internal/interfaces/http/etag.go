@@ -0,0 +1,41 @@
+package httpapi
+
+import (
+	"strconv"
+	"strings"
+)
+
+// etag renders a subscription version as a strong entity tag.
+func etag(version int32) string {
+	return `"` + strconv.FormatInt(int64(version), 10) + `"`
+}
+
+// noneMatch reports whether an If-None-Match header matches version,
+// using weak comparison as RFC 9110 requires.
+func noneMatch(header string, version int32) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIfMatch extracts the version required by an If-Match header.
+// "*" yields nil (any version). Only a single strong tag is supported.
+func parseIfMatch(header string) (*int32, bool) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return nil, true
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return nil, false
+	}
+	v, err := strconv.ParseInt(header[1:len(header)-1], 10, 32)
+	if err != nil {
+		return nil, false
+	}
+	version := int32(v)
+	return &version, true
+}
@@ -0,0 +1,157 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/Neroframe/sub_crudl/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// exportFlushRows is how many rows are buffered before flushing to the client.
+const exportFlushRows = 100
+
+// exportBatchTimeout bounds the write of one batch. The server WriteTimeout
+// would otherwise cut off any export that takes longer than a normal request,
+// so the deadline is pushed forward before every batch instead.
+const exportBatchTimeout = 30 * time.Second
+
+// csvExportColumns is the CSV header; dates use the MM-YYYY request format.
+var csvExportColumns = []string{
+	"id", "service_name", "user_id", "start_date", "end_date", "price", "currency", "billing_period",
+}
+
+// ExportSubscriptions godoc
+// @Summary     Export subscriptions
+// @Description Stream every subscription matching the filters, in the same order as the list endpoint. CSV has a header row and MM-YYYY dates; NDJSON has one create payload per line. Both can be fed back to /subscriptions/import.
+// @Description A failure after streaming has started can only end the body early; no error document follows.
+// @Tags        subscriptions
+// @Produce     text/csv
+// @Produce     application/x-ndjson
+// @Param       format       query string false "csv (default) or ndjson" Enums(csv, ndjson)
+// @Param       user_id      query string false "User ID"
+// @Param       service_name query string false "Service Name"
+// @Success     200 {string} string "CSV or NDJSON document"
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /subscriptions/export [get]
+func (h *Handler) ExportSubscriptions(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "ExportSubscriptions")
+
+	format := c.DefaultQuery("format", "csv")
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = csvContentType
+	case "ndjson":
+		contentType = ndjsonContentType
+	default:
+		writeProblem(c, log, malformed("format", "format must be csv or ndjson"), "Invalid format")
+		return
+	}
+
+	userID, serviceName, ok := parseSubscriptionFilter(c, log)
+	if !ok {
+		return
+	}
+	filter := appdto.ExportFilter{UserID: userID, ServiceName: serviceName}
+
+	rc := http.NewResponseController(c.Writer)
+	extendDeadline := func() {
+		// Recorders and some wrappers cannot set deadlines; the server
+		// timeout then applies as before.
+		_ = rc.SetWriteDeadline(time.Now().Add(exportBatchTimeout))
+	}
+
+	cw := csv.NewWriter(c.Writer)
+	enc := json.NewEncoder(c.Writer)
+
+	// Headers go out with the first row, so a failure before that can
+	// still be reported as a normal error response.
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		extendDeadline()
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="subscriptions.`+format+`"`)
+		c.Status(http.StatusOK)
+		if format == "csv" {
+			return cw.Write(csvExportColumns)
+		}
+		return nil
+	}
+
+	count := 0
+	err := h.SubService.Export(c.Request.Context(), filter, func(sub *domain.Subscription) error {
+		if err := start(); err != nil {
+			return err
+		}
+		count++
+		if format == "ndjson" {
+			if err := enc.Encode(toCreateSubscriptionDTO(sub)); err != nil {
+				return err
+			}
+		} else if err := cw.Write(csvRecord(sub)); err != nil {
+			return err
+		}
+		if count%exportFlushRows == 0 {
+			cw.Flush()
+			c.Writer.Flush()
+			extendDeadline()
+			return cw.Error()
+		}
+		return nil
+	})
+	if err == nil {
+		err = start()
+	}
+	if format == "csv" && started {
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+	}
+
+	if err != nil {
+		if !started {
+			writeProblem(c, log, err, "Failed to export subscriptions")
+			return
+		}
+		// The status line is already sent; all we can do is stop writing
+		log.Error("export aborted", "error", err, "rows", count)
+		c.Abort()
+		return
+	}
+
+	log.Info("subscriptions exported", "format", format, "rows", count)
+}
+
+func csvRecord(sub *domain.Subscription) []string {
+	endDate := ""
+	if sub.EndDate != nil {
+		endDate = sub.EndDate.Format("01-2006")
+	}
+	return []string{
+		sub.ID.String(),
+		sub.ServiceName,
+		sub.UserID.String(),
+		sub.StartDate.Format("01-2006"),
+		endDate,
+		strconv.FormatInt(int64(sub.Price), 10),
+		sub.Currency,
+		string(sub.BillingPeriod),
+	}
+}
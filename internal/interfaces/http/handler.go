@@ -1,27 +1,45 @@
 package httpapi
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Neroframe/sub_crudl/internal/app"
 	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
 	"github.com/Neroframe/sub_crudl/internal/interfaces/http/dto"
 	"github.com/Neroframe/sub_crudl/pkg/logger"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 )
 
-type ErrorResponse struct {
-	Error string `json:"error" example:"Invalid request"`
-}
+const (
+	// IdempotencyKeyHeader makes POST /subscriptions safe to retry.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed for a repeated key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
 
 type AggregateResponse struct {
-	Total int `json:"total" example:"123"`
+	Total    int64               `json:"total" example:"123"`
+	Currency string              `json:"currency" example:"RUB"`
+	Groups   []dto.CostBucketDTO `json:"groups,omitempty"` // present only when group_by is set
+}
+
+type TimeSeriesResponse struct {
+	Currency string               `json:"currency" example:"RUB"`
+	Points   []dto.MonthlyCostDTO `json:"points"`
+}
+
+type ListResponse struct {
+	Items      []dto.SubscriptionDTO `json:"items"`
+	NextCursor string                `json:"next_cursor" example:"MjAyNS0wMS0wMXwxMjNlNDU2Ny1lODliLTEyZDMtYTQ1Ni00MjY2MTQxNzQwMDA"`
 }
 
 type Handler struct {
@@ -36,75 +54,100 @@ func NewHandler(subService app.SubscriptionService, logger *logger.Logger) *Hand
 // CreateSubscription godoc
 // @Summary     Create a new subscription
 // @Description Create subscription with service name, price, user ID, start and optional end date
+// @Description user_id defaults to the caller's token subject; only admins may create subscriptions for other users.
+// @Description With an Idempotency-Key, repeating the request returns the originally created subscription (marked by an Idempotent-Replayed header) instead of creating another; reusing the key with a different body is rejected.
 // @Tags        subscriptions
 // @Accept      json
 // @Produce     json
-// @Param       subscription body dto.CreateSubscriptionDTO true "Subscription data"
+// @Param       subscription    body   dto.CreateSubscriptionDTO true  "Subscription data"
+// @Param       Idempotency-Key header string                    false "Unique key for safely retrying the request, up to 255 characters"
 // @Success     201 {object} dto.SubscriptionDTO
-// @Failure     400 {object} httpapi.ErrorResponse
-// @Failure     500 {object} httpapi.ErrorResponse
+// @Header      201 {string} Idempotent-Replayed "true when the response is a replay"
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     422 {object} httpapi.Problem "Validation failed, or Idempotency-Key was used for a different request"
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /subscriptions [post]
 func (h *Handler) CreateSubscription(c *gin.Context) {
-	log := h.log.With("handler", "CreateSubscription")
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "CreateSubscription")
 	log.Debug("parsing request")
 
 	var req dto.CreateSubscriptionDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Error("invalid request body", "error", err)
-		var ve validator.ValidationErrors
-		if errors.As(err, &ve) {
-			fieldErr := ve[0]
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s failed %s validation", fieldErr.Field(), fieldErr.Tag())})
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		}
+		writeProblem(c, log, bindError(err), "Invalid request body")
 		return
 	}
 
 	log.Debug("parsed request", "service_name", req.ServiceName, "user_id", req.UserID)
 
-	userID, err := uuid.Parse(req.UserID)
+	input, err := toCreateInput(req)
 	if err != nil {
-		log.Error("invalid user_id format", "user_id", req.UserID, "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+		writeProblem(c, log, err, "Invalid request body")
 		return
 	}
 
-	startDate, err := time.Parse("01-2006", req.StartDate)
+	var sub *domain.Subscription
+	replayed := false
+	if key, ok := c.Request.Header[IdempotencyKeyHeader]; ok {
+		sub, replayed, err = h.SubService.CreateIdempotent(c.Request.Context(), key[0], input)
+	} else {
+		sub, err = h.SubService.Create(c.Request.Context(), input)
+	}
 	if err != nil {
-		log.Error("invalid start_date format", "start_date", req.StartDate, "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date"})
+		writeProblem(c, log, err, "Failed to create subscription")
 		return
 	}
 
-	var endDate *time.Time
-	if req.EndDate != "" {
-		t, err2 := time.Parse("01-2006", req.EndDate)
-		if err2 != nil {
-			log.Error("invalid end_date format", "end_date", req.EndDate, "error", err2)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date"})
-			return
-		}
-		endDate = &t
+	if replayed {
+		log.Info("subscription create replayed", "id", sub.ID, "user", sub.UserID)
+		c.Header(IdempotentReplayedHeader, "true")
+	} else {
+		log.Info("subscription created", "id", sub.ID, "user", sub.UserID)
 	}
+	c.Header("ETag", etag(sub.Version))
+	c.JSON(http.StatusCreated, toSubscriptionDTO(sub))
+}
 
-	input := appdto.CreateInput{
-		ServiceName: req.ServiceName,
-		UserID:      userID,
-		StartDate:   startDate,
-		EndDate:     endDate,
-		Price:       req.Price,
+// toCreateInput converts the wire format shared by create and import.
+func toCreateInput(req dto.CreateSubscriptionDTO) (appdto.CreateInput, error) {
+	var input appdto.CreateInput
+
+	var userID uuid.UUID // left empty, the service assigns the caller
+	if req.UserID != "" {
+		id, err := uuid.Parse(req.UserID)
+		if err != nil {
+			return input, malformed("user_id", "user_id must be a UUID")
+		}
+		userID = id
 	}
 
-	sub, err := h.SubService.Create(c.Request.Context(), input)
+	startDate, err := time.Parse("01-2006", req.StartDate)
 	if err != nil {
-		log.Error("failed to create subscription", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subscription"})
-		return
+		return input, malformed("start_date", "start_date must be in MM-YYYY format")
 	}
 
-	log.Info("subscription created", "id", sub.ID, "user", sub.UserID)
-	c.JSON(http.StatusCreated, sub)
+	var endDate *time.Time
+	if req.EndDate != "" {
+		t, err := time.Parse("01-2006", req.EndDate)
+		if err != nil {
+			return input, malformed("end_date", "end_date must be in MM-YYYY format")
+		}
+		endDate = &t
+	}
+
+	return appdto.CreateInput{
+		ServiceName:   req.ServiceName,
+		UserID:        userID,
+		StartDate:     startDate,
+		EndDate:       endDate,
+		Price:         *req.Price, // set, as binding requires it
+		Currency:      req.Currency,
+		BillingPeriod: domain.BillingPeriod(req.BillingPeriod),
+	}, nil
 }
 
 // GetSubscription godoc
@@ -112,186 +155,316 @@ func (h *Handler) CreateSubscription(c *gin.Context) {
 // @Description Retrieve subscription details by subscription ID
 // @Tags        subscriptions
 // @Produce     json
-// @Param       id path string true "Subscription ID"
+// @Param       id            path   string true  "Subscription ID"
+// @Param       If-None-Match header string false "ETag from a previous response"
 // @Success     200 {object} dto.SubscriptionDTO
-// @Failure     400 {object} httpapi.ErrorResponse
-// @Failure     404 {object} httpapi.ErrorResponse
-// @Failure     500 {object} httpapi.ErrorResponse
+// @Success     304 {object} nil
+// @Header      200 {string} ETag "Subscription version"
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     404 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /subscriptions/{id} [get]
 func (h *Handler) GetSubscription(c *gin.Context) {
-	log := h.log.With("handler", "GetSubscription")
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "GetSubscription")
 
 	idStr := c.Param("id")
 	log.Debug("received get request", "id", idStr)
 
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		log.Error("invalid subscription ID format", "id", idStr, "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		writeProblem(c, log, malformed("id", "id must be a UUID"), "Invalid subscription ID")
 		return
 	}
 
 	sub, err := h.SubService.Get(c.Request.Context(), id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			log.Info("subscription not found", "id", id)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
-		} else {
-			log.Error("failed to retrieve subscription", "id", id, "error", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve subscription"})
-		}
+		writeProblem(c, log.With("id", id), err, "Failed to retrieve subscription")
+		return
+	}
+
+	c.Header("ETag", etag(sub.Version))
+	if inm := c.GetHeader("If-None-Match"); inm != "" && noneMatch(inm, sub.Version) {
+		log.Debug("subscription not modified", "id", sub.ID, "version", sub.Version)
+		c.Status(http.StatusNotModified)
 		return
 	}
 
 	log.Info("subscription retrieved", "id", sub.ID)
-	c.JSON(http.StatusOK, sub)
+	c.JSON(http.StatusOK, toSubscriptionDTO(sub))
 }
 
 // ListSubscriptions godoc
 // @Summary     List subscriptions
-// @Description Get all subscriptions, optionally filter by user_id and service_name
+// @Description Get a page of subscriptions ordered by start date (newest first), optionally filtered by user_id and service_name. Pass next_cursor from the previous response as cursor to fetch the following page.
 // @Tags        subscriptions
 // @Produce     json
 // @Param       user_id      query string false "User ID"
 // @Param       service_name query string false "Service Name"
-// @Success     200 {array}  dto.SubscriptionDTO
-// @Failure     500 {object} httpapi.ErrorResponse
+// @Param       limit        query int    false "Page size (default 50, max 500)"
+// @Param       cursor       query string false "Opaque cursor from a previous page"
+// @Success     200 {object} httpapi.ListResponse
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     422 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /subscriptions [get]
 func (h *Handler) ListSubscriptions(c *gin.Context) {
-	log := h.log.With("handler", "ListSubscriptions")
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "ListSubscriptions")
 
 	userIDStr := c.Query("user_id")
 	serviceNameStr := c.Query("service_name")
-	log.Debug("received list request", "user_id", userIDStr, "service_name", serviceNameStr)
+	limitStr := c.Query("limit")
+	cursor := c.Query("cursor")
+	log.Debug("received list request", "user_id", userIDStr, "service_name", serviceNameStr, "limit", limitStr, "cursor", cursor)
 
-	var userID *uuid.UUID
-	if userIDStr != "" {
-		parsed, err := uuid.Parse(userIDStr)
-		if err != nil {
-			log.Error("invalid user_id format", "user_id", userIDStr, "error", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+	userID, serviceName, ok := parseSubscriptionFilter(c, log)
+	if !ok {
+		return
+	}
+
+	var limit int32
+	if limitStr != "" {
+		parsed, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil || parsed <= 0 {
+			writeProblem(c, log, malformed("limit", "limit must be a positive integer"), "Invalid limit")
 			return
 		}
-		userID = &parsed
+		limit = int32(parsed)
 	}
 
-	var serviceName *string
-	if serviceNameStr != "" {
-		serviceName = &serviceNameStr
+	filter := appdto.ListFilter{
+		UserID:      userID,
+		ServiceName: serviceName,
+		Limit:       limit,
+		Cursor:      cursor,
 	}
 
-	subs, err := h.SubService.List(c.Request.Context(), userID, serviceName)
+	page, err := h.SubService.List(c.Request.Context(), filter)
 	if err != nil {
-		log.Error("failed to list subscriptions", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscriptions"})
+		writeProblem(c, log, err, "Failed to fetch subscriptions")
 		return
 	}
 
-	log.Info("subscriptions listed", "count", len(subs))
-	c.JSON(http.StatusOK, subs)
+	log.Info("subscriptions listed", "count", len(page.Items))
+	c.JSON(http.StatusOK, ListResponse{Items: toSubscriptionDTOs(page.Items), NextCursor: page.NextCursor})
+}
+
+// parseSubscriptionFilter reads the user_id and service_name filters shared by
+// list and export. It writes the problem response itself and reports ok=false.
+func parseSubscriptionFilter(c *gin.Context, log *slog.Logger) (userID *uuid.UUID, serviceName *string, ok bool) {
+	if s := c.Query("user_id"); s != "" {
+		parsed, err := uuid.Parse(s)
+		if err != nil {
+			writeProblem(c, log, malformed("user_id", "user_id must be a UUID"), "Invalid user_id")
+			return nil, nil, false
+		}
+		userID = &parsed
+	}
+	if s := c.Query("service_name"); s != "" {
+		serviceName = &s
+	}
+	return userID, serviceName, true
 }
 
 // UpdateSubscription godoc
-// @Summary     Update a subscription
-// @Description Update subscription fields by ID
+// @Summary     Replace a subscription
+// @Description Replace all editable fields of a subscription by ID. Omitting end_date makes the subscription open-ended; omitted currency and billing_period reset to RUB and monthly. Use PATCH for partial updates.
 // @Tags        subscriptions
 // @Accept      json
 // @Produce     json
-// @Param       id           path string               true  "Subscription ID"
-// @Param       subscription body dto.UpdateSubscriptionDTO true "Updated subscription data"
+// @Param       id           path   string                    true  "Subscription ID"
+// @Param       If-Match     header string                    false "ETag the update is based on"
+// @Param       subscription body   dto.UpdateSubscriptionDTO true  "Updated subscription data"
 // @Success     200 {object} dto.SubscriptionDTO
-// @Failure     400 {object} httpapi.ErrorResponse
-// @Failure     500 {object} httpapi.ErrorResponse
+// @Header      200 {string} ETag "New subscription version"
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     404 {object} httpapi.Problem
+// @Failure     409 {object} httpapi.Problem
+// @Failure     412 {object} httpapi.Problem
+// @Failure     422 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /subscriptions/{id} [put]
 func (h *Handler) UpdateSubscription(c *gin.Context) {
-	log := h.log.With("handler", "UpdateSubscription")
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "UpdateSubscription")
 	idStr := c.Param("id")
 	log.Debug("received update request", "id", idStr)
 
 	subID, err := uuid.Parse(idStr)
 	if err != nil {
-		log.Error("invalid subscription ID format", "id", idStr, "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		writeProblem(c, log, malformed("id", "id must be a UUID"), "Invalid subscription ID")
 		return
 	}
 
 	var req dto.UpdateSubscriptionDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Error("invalid update payload", "error", err)
-		var ve validator.ValidationErrors
-		if errors.As(err, &ve) {
-			fieldErr := ve[0]
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s failed %s validation", fieldErr.Field(), fieldErr.Tag())})
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		}
+		writeProblem(c, log, bindError(err), "Invalid request body")
+		return
+	}
+
+	startDate, err := time.Parse("01-2006", req.StartDate)
+	if err != nil {
+		writeProblem(c, log, malformed("start_date", "start_date must be in MM-YYYY format"), "Invalid start_date")
 		return
 	}
 
-	var startDate *time.Time
-	if req.StartDate != nil {
-		t, err2 := time.Parse("01-2006", *req.StartDate)
+	// Full replacement: a missing end_date reopens the subscription
+	input := appdto.UpdateInput{
+		ServiceName:  &req.ServiceName,
+		StartDate:    &startDate,
+		Price:        req.Price,
+		Currency:     &req.Currency,
+		ClearEndDate: req.EndDate == "",
+	}
+	if req.EndDate != "" {
+		t, err2 := time.Parse("01-2006", req.EndDate)
 		if err2 != nil {
-			log.Error("invalid start_date format", "start_date", *req.StartDate, "error", err2)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date"})
+			writeProblem(c, log, malformed("end_date", "end_date must be in MM-YYYY format"), "Invalid end_date")
 			return
 		}
-		startDate = &t
+		input.EndDate = &t
+	}
+	period := domain.BillingPeriod(req.BillingPeriod)
+	if period == "" {
+		period = domain.BillingMonthly
 	}
+	input.BillingPeriod = &period
 
-	var endDate *time.Time
-	if req.EndDate != nil {
-		t, err2 := time.Parse("01-2006", *req.EndDate)
-		if err2 != nil {
-			log.Error("invalid end_date format", "end_date", *req.EndDate, "error", err2)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date"})
-			return
+	h.applyUpdate(c, log, subID, input)
+}
+
+// PatchSubscription godoc
+// @Summary     Partially update a subscription
+// @Description Apply a JSON Merge Patch (RFC 7396) to a subscription. Omitted fields are left unchanged; "end_date": null makes the subscription open-ended. Other fields cannot be null.
+// @Tags        subscriptions
+// @Accept      application/merge-patch+json
+// @Produce     json
+// @Param       id       path   string                         true  "Subscription ID"
+// @Param       If-Match header string                         false "ETag the update is based on"
+// @Param       patch    body   dto.PatchSubscriptionDTO true  "Merge patch"
+// @Success     200 {object} dto.SubscriptionDTO
+// @Header      200 {string} ETag "New subscription version"
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     404 {object} httpapi.Problem
+// @Failure     409 {object} httpapi.Problem
+// @Failure     412 {object} httpapi.Problem
+// @Failure     413 {object} httpapi.Problem
+// @Failure     415 {object} httpapi.Problem
+// @Failure     422 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /subscriptions/{id} [patch]
+func (h *Handler) PatchSubscription(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "PatchSubscription")
+	idStr := c.Param("id")
+	log.Debug("received patch request", "id", idStr)
+
+	subID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeProblem(c, log, malformed("id", "id must be a UUID"), "Invalid subscription ID")
+		return
+	}
+
+	if ct := c.ContentType(); ct != mergePatchContentType && ct != gin.MIMEJSON {
+		writeProblem(c, log, unsupportedMediaType("Content-Type must be "+mergePatchContentType), "Unsupported media type")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = requestTooLarge(fmt.Sprintf("merge patch exceeds %d bytes", tooLarge.Limit))
+		} else {
+			err = malformed("", "request body could not be read")
 		}
-		endDate = &t
+		writeProblem(c, log, err, "Invalid request body")
+		return
 	}
 
-	input := appdto.UpdateInput{
-		ServiceName: req.ServiceName,
-		StartDate:   startDate,
-		EndDate:     endDate,
-		Price:       req.Price,
+	input, err := parseMergePatch(body)
+	if err != nil {
+		writeProblem(c, log, err, "Invalid merge patch")
+		return
+	}
+
+	h.applyUpdate(c, log, subID, input)
+}
+
+// applyUpdate runs the update shared by PUT and PATCH, honoring If-Match,
+// and writes the response.
+func (h *Handler) applyUpdate(c *gin.Context, log *slog.Logger, subID uuid.UUID, input appdto.UpdateInput) {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch != "" {
+		version, ok := parseIfMatch(ifMatch)
+		if !ok {
+			writeProblem(c, log, preconditionFailed("If-Match does not match the subscription"), "Precondition failed")
+			return
+		}
+		input.IfVersion = version
 	}
 
 	sub, err := h.SubService.Update(c.Request.Context(), subID, input)
 	if err != nil {
-		log.Error("failed to update subscription", "id", subID, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subscription"})
+		// With If-Match the client asked for a version check, so a conflict
+		// is a failed precondition rather than a race
+		if errors.Is(err, app.ErrConflict) && ifMatch != "" {
+			err = preconditionFailed("If-Match does not match the subscription")
+		}
+		writeProblem(c, log.With("id", subID), err, "Failed to update subscription")
 		return
 	}
 
-	log.Info("subscription updated", "id", sub.ID)
-	c.JSON(http.StatusOK, sub)
+	log.Info("subscription updated", "id", sub.ID, "version", sub.Version)
+	c.Header("ETag", etag(sub.Version))
+	c.JSON(http.StatusOK, toSubscriptionDTO(sub))
 }
 
 // DeleteSubscription godoc
 // @Summary     Delete a subscription
-// @Description Delete subscription by ID
+// @Description Move subscription to the trash by ID. It can be restored until the trash retention period expires.
 // @Tags        subscriptions
 // @Param       id   path   string true "Subscription ID"
 // @Success     204 {object} nil
-// @Failure     400 {object} httpapi.ErrorResponse
-// @Failure     500 {object} httpapi.ErrorResponse
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     404 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /subscriptions/{id} [delete]
 func (h *Handler) DeleteSubscription(c *gin.Context) {
-	log := h.log.With("handler", "DeleteSubscription")
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "DeleteSubscription")
 	idStr := c.Param("id")
 	log.Debug("received delete request", "id", idStr)
 
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		log.Error("invalid subscription ID format", "id", idStr, "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		writeProblem(c, log, malformed("id", "id must be a UUID"), "Invalid subscription ID")
 		return
 	}
 
 	if err := h.SubService.Delete(c.Request.Context(), id); err != nil {
-		log.Error("failed to delete subscription", "id", id, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete subscription"})
+		writeProblem(c, log.With("id", id), err, "Failed to delete subscription")
 		return
 	}
 
@@ -299,35 +472,287 @@ func (h *Handler) DeleteSubscription(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// RestoreSubscription godoc
+// @Summary     Restore a deleted subscription
+// @Description Take a subscription out of the trash
+// @Tags        subscriptions
+// @Produce     json
+// @Param       id   path   string true "Subscription ID"
+// @Success     200 {object} dto.SubscriptionDTO
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     404 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /subscriptions/{id}/restore [post]
+func (h *Handler) RestoreSubscription(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "RestoreSubscription")
+	idStr := c.Param("id")
+	log.Debug("received restore request", "id", idStr)
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeProblem(c, log, malformed("id", "id must be a UUID"), "Invalid subscription ID")
+		return
+	}
+
+	sub, err := h.SubService.Restore(c.Request.Context(), id)
+	if err != nil {
+		writeProblem(c, log.With("id", id), err, "Failed to restore subscription")
+		return
+	}
+
+	log.Info("subscription restored", "id", sub.ID)
+	c.JSON(http.StatusOK, toSubscriptionDTO(sub))
+}
+
+// GetSubscriptionHistory godoc
+// @Summary     Get subscription change history
+// @Description List every create, update, delete and restore of a subscription with before/after snapshots, actor and timestamp, oldest first
+// @Tags        subscriptions
+// @Produce     json
+// @Param       id   path   string true "Subscription ID"
+// @Success     200 {array}  dto.SubscriptionEventDTO
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /subscriptions/{id}/history [get]
+func (h *Handler) GetSubscriptionHistory(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "GetSubscriptionHistory")
+	idStr := c.Param("id")
+	log.Debug("received history request", "id", idStr)
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeProblem(c, log, malformed("id", "id must be a UUID"), "Invalid subscription ID")
+		return
+	}
+
+	events, err := h.SubService.History(c.Request.Context(), id)
+	if err != nil {
+		writeProblem(c, log.With("id", id), err, "Failed to fetch subscription history")
+		return
+	}
+
+	log.Info("subscription history fetched", "id", id, "count", len(events))
+	out := make([]dto.SubscriptionEventDTO, 0, len(events))
+	for _, e := range events {
+		out = append(out, toEventDTO(e))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// ListDeletedSubscriptions godoc
+// @Summary     List deleted subscriptions
+// @Description Get subscriptions in the trash, most recently deleted first, optionally filtered by user_id
+// @Tags        subscriptions
+// @Produce     json
+// @Param       user_id query string false "User ID"
+// @Param       limit   query int    false "Maximum number of results (default 50, max 500)"
+// @Success     200 {array}  dto.SubscriptionDTO
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     422 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /subscriptions/trash [get]
+func (h *Handler) ListDeletedSubscriptions(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "ListDeletedSubscriptions")
+
+	userIDStr := c.Query("user_id")
+	limitStr := c.Query("limit")
+	log.Debug("received trash list request", "user_id", userIDStr, "limit", limitStr)
+
+	var userID *uuid.UUID
+	if userIDStr != "" {
+		parsed, err := uuid.Parse(userIDStr)
+		if err != nil {
+			writeProblem(c, log, malformed("user_id", "user_id must be a UUID"), "Invalid user_id")
+			return
+		}
+		userID = &parsed
+	}
+
+	var limit int32
+	if limitStr != "" {
+		parsed, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil || parsed <= 0 {
+			writeProblem(c, log, malformed("limit", "limit must be a positive integer"), "Invalid limit")
+			return
+		}
+		limit = int32(parsed)
+	}
+
+	subs, err := h.SubService.ListDeleted(c.Request.Context(), userID, limit)
+	if err != nil {
+		writeProblem(c, log, err, "Failed to fetch deleted subscriptions")
+		return
+	}
+
+	log.Info("deleted subscriptions listed", "count", len(subs))
+	c.JSON(http.StatusOK, toSubscriptionDTOs(subs))
+}
+
 // AggregateSubscriptions godoc
 // @Summary     Aggregate subscription costs
-// @Description Calculate total cost over period with optional filters
+// @Description Calculate total cost over period with optional filters. Each subscription contributes its price for every renewal (per its billing_period) that falls in a month it is active within the period, or its monthly-equivalent price for each active month when normalize is set. The period may span at most 120 months.
 // @Tags        subscriptions
 // @Produce     json
 // @Param       user_id      query string false "User ID"
 // @Param       service_name query string false "Service Name"
 // @Param       start_period query string true  "Start period (MM-YYYY)"
 // @Param       end_period   query string true  "End period (MM-YYYY)"
+// @Param       currency     query string false "Report currency (ISO 4217, default RUB)"
+// @Param       normalize    query bool   false "Report monthly-equivalent cost instead of actual charges"
+// @Param       group_by     query string false "Comma-separated grouping: service_name, user_id, month"
 // @Success     200 {object} httpapi.AggregateResponse
-// @Failure     400 {object} httpapi.ErrorResponse
-// @Failure     500 {object} httpapi.ErrorResponse
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     422 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
 // @Router      /subscriptions/aggregate [get]
 func (h *Handler) AggregateSubscriptions(c *gin.Context) {
-	log := h.log.With("handler", "AggregateSubscriptions")
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "AggregateSubscriptions")
+
+	groupByStr := c.Query("group_by")
+	filter, ok := h.parseAggregationFilter(c, log)
+	if !ok {
+		return
+	}
+	log.Debug("received aggregate request", "filter", filter, "group_by", groupByStr)
 
+	var groupBy []appdto.GroupField
+	if groupByStr != "" {
+		for _, field := range strings.Split(groupByStr, ",") {
+			groupBy = append(groupBy, appdto.GroupField(strings.TrimSpace(field)))
+		}
+	}
+	filter.GroupBy = groupBy
+
+	if len(groupBy) > 0 {
+		buckets, err := h.SubService.AggregateGrouped(c.Request.Context(), filter)
+		if err != nil {
+			writeProblem(c, log, err, "Failed to calculate aggregate")
+			return
+		}
+
+		var total int64
+		groups := make([]dto.CostBucketDTO, 0, len(buckets))
+		for _, b := range buckets {
+			total += b.Total
+			groups = append(groups, toCostBucketDTO(b))
+		}
+
+		log.Info("grouped aggregate calculated", "total", total, "buckets", len(groups))
+		c.JSON(http.StatusOK, gin.H{"total": total, "currency": reportCurrency(filter), "groups": groups})
+		return
+	}
+
+	sum, err := h.SubService.Aggregate(c.Request.Context(), filter)
+	if err != nil {
+		writeProblem(c, log, err, "Failed to calculate aggregate")
+		return
+	}
+
+	log.Info("aggregate calculated", "total", sum)
+	c.JSON(http.StatusOK, gin.H{"total": sum, "currency": reportCurrency(filter)})
+}
+
+func toCostBucketDTO(b *appdto.CostBucket) dto.CostBucketDTO {
+	out := dto.CostBucketDTO{
+		ServiceName:   b.ServiceName,
+		Total:         b.Total,
+		Subscriptions: b.Subscriptions,
+	}
+	if b.UserID != nil {
+		id := b.UserID.String()
+		out.UserID = &id
+	}
+	if b.Month != nil {
+		month := b.Month.Format("01-2006")
+		out.Month = &month
+	}
+	return out
+}
+
+// AggregateTimeSeries godoc
+// @Summary     Monthly spend time series
+// @Description Total active cost for every month between start_period and end_period (inclusive), with the same optional filters as the aggregate endpoint. Months without spend are reported as 0. The window may span at most 120 months.
+// @Tags        subscriptions
+// @Produce     json
+// @Param       user_id      query string false "User ID"
+// @Param       service_name query string false "Service Name"
+// @Param       start_period query string true  "Start period (MM-YYYY)"
+// @Param       end_period   query string true  "End period (MM-YYYY)"
+// @Param       currency     query string false "Report currency (ISO 4217, default RUB)"
+// @Param       normalize    query bool   false "Report monthly-equivalent cost instead of actual charges"
+// @Success     200 {object} httpapi.TimeSeriesResponse
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     422 {object} httpapi.Problem
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /subscriptions/aggregate/timeseries [get]
+func (h *Handler) AggregateTimeSeries(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "AggregateTimeSeries")
+
+	filter, ok := h.parseAggregationFilter(c, log)
+	if !ok {
+		return
+	}
+	log.Debug("received timeseries request", "filter", filter)
+
+	series, err := h.SubService.TimeSeries(c.Request.Context(), filter)
+	if err != nil {
+		writeProblem(c, log, err, "Failed to calculate timeseries")
+		return
+	}
+
+	points := make([]dto.MonthlyCostDTO, 0, len(series))
+	for _, p := range series {
+		points = append(points, dto.MonthlyCostDTO{
+			Month: p.Month.Format("01-2006"),
+			Total: p.Total,
+		})
+	}
+
+	log.Info("timeseries calculated", "points", len(points))
+	c.JSON(http.StatusOK, gin.H{"currency": reportCurrency(filter), "points": points})
+}
+
+// parseAggregationFilter reads the query parameters shared by the aggregate
+// endpoints. On failure it writes a 400 problem response and returns false.
+func (h *Handler) parseAggregationFilter(c *gin.Context, log *slog.Logger) (appdto.AggregationFilter, bool) {
 	userIDStr := c.Query("user_id")
 	serviceNameStr := c.Query("service_name")
 	startStr := c.Query("start_period")
 	endStr := c.Query("end_period")
-	log.Debug("received aggregate request", "user_id", userIDStr, "service_name", serviceNameStr, "start", startStr, "end", endStr)
+	currency := strings.ToUpper(c.Query("currency"))
+	normalizeStr := c.Query("normalize")
 
 	var userID *uuid.UUID
 	if userIDStr != "" {
 		parsed, err := uuid.Parse(userIDStr)
 		if err != nil {
-			log.Error("invalid user_id format", "user_id", userIDStr, "error", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
-			return
+			writeProblem(c, log, malformed("user_id", "user_id must be a UUID"), "Invalid user_id")
+			return appdto.AggregationFilter{}, false
 		}
 		userID = &parsed
 	}
@@ -339,32 +764,39 @@ func (h *Handler) AggregateSubscriptions(c *gin.Context) {
 
 	start, err := time.Parse("01-2006", startStr)
 	if err != nil {
-		log.Error("invalid start_period format", "start_period", startStr, "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_period"})
-		return
+		writeProblem(c, log, malformed("start_period", "start_period must be in MM-YYYY format"), "Invalid start_period")
+		return appdto.AggregationFilter{}, false
 	}
 
 	end, err := time.Parse("01-2006", endStr)
 	if err != nil {
-		log.Error("invalid end_period format", "end_period", endStr, "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_period"})
-		return
+		writeProblem(c, log, malformed("end_period", "end_period must be in MM-YYYY format"), "Invalid end_period")
+		return appdto.AggregationFilter{}, false
 	}
 
-	filter := appdto.AggregationFilter{
+	var normalize bool
+	if normalizeStr != "" {
+		normalize, err = strconv.ParseBool(normalizeStr)
+		if err != nil {
+			writeProblem(c, log, malformed("normalize", "normalize must be a boolean"), "Invalid normalize")
+			return appdto.AggregationFilter{}, false
+		}
+	}
+
+	return appdto.AggregationFilter{
 		UserID:      userID,
 		ServiceName: serviceName,
 		StartPeriod: start,
 		EndPeriod:   end,
-	}
+		Currency:    currency,
+		Normalize:   normalize,
+	}, true
+}
 
-	sum, err := h.SubService.Aggregate(c.Request.Context(), filter)
-	if err != nil {
-		log.Error("aggregate calculation failed", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate aggregate"})
-		return
+// reportCurrency is the currency the service reports totals in for filter.
+func reportCurrency(filter appdto.AggregationFilter) string {
+	if filter.Currency == "" {
+		return app.DefaultCurrency
 	}
-
-	log.Info("aggregate calculated", "total", sum)
-	c.JSON(http.StatusOK, gin.H{"total": sum})
+	return filter.Currency
 }
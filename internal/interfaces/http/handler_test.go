@@ -0,0 +1,116 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	"github.com/Neroframe/sub_crudl/internal/infra/fx"
+	"github.com/Neroframe/sub_crudl/internal/infra/memory"
+	"github.com/Neroframe/sub_crudl/internal/interfaces/http/dto"
+	"github.com/Neroframe/sub_crudl/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter serves the API from the memory repositories with
+// authentication disabled, so every caller acts as an admin.
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	log := logger.New(logger.Config{Level: "error", SourceFolder: "sub_crudl"})
+
+	rates, err := fx.LoadCSV("../../../config/rates.csv")
+	if err != nil {
+		t.Fatalf("load rates: %v", err)
+	}
+	subs := app.NewSubscriptionService(memory.NewSubscriptionRepo(), rates, log)
+	keys := app.NewAPIKeyService(memory.NewAPIKeyRepo(), log)
+	roles := app.NewRoleService(memory.NewUserRoleRepo(), log)
+
+	r := gin.New()
+	r.Use(RequestIDMiddleware(log))
+	RegisterRoutes(r, NewHandler(subs, log), NewAPIKeyHandler(keys, log),
+		NewAuthenticator(nil, keys, roles, log), NewRateLimiter(nil, nil, nil, nil, log), NewHealthHandler(0, log))
+	return r
+}
+
+func serve(r *gin.Engine, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", gin.MIMEJSON)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decodeSubscription(t *testing.T, w *httptest.ResponseRecorder) dto.SubscriptionDTO {
+	t.Helper()
+	var sub dto.SubscriptionDTO
+	if err := json.Unmarshal(w.Body.Bytes(), &sub); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	return sub
+}
+
+func TestCreateSubscriptionIdempotency(t *testing.T) {
+	r := newTestRouter(t)
+	body := `{"service_name":"Yandex Plus","user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"07-2025","price":0,"currency":"usd"}`
+
+	first := serve(r, http.MethodPost, "/subscriptions", body, IdempotencyKeyHeader, "k1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first create: status %d, body %s", first.Code, first.Body)
+	}
+	created := decodeSubscription(t, first)
+	if created.Currency != "USD" || created.BillingPeriod != "monthly" || created.Price != 0 {
+		t.Errorf("created = %+v, want USD monthly at price 0", created)
+	}
+
+	// The same request once defaults and case are applied
+	same := `{"service_name":"Yandex Plus","user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"07-2025","price":0,"currency":"USD","billing_period":"monthly"}`
+	replay := serve(r, http.MethodPost, "/subscriptions", same, IdempotencyKeyHeader, "k1")
+	if replay.Code != http.StatusCreated || replay.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("replay: status %d, replayed %q", replay.Code, replay.Header().Get(IdempotentReplayedHeader))
+	}
+	if got := decodeSubscription(t, replay); got.ID != created.ID {
+		t.Errorf("replay returned %s, want %s", got.ID, created.ID)
+	}
+
+	other := serve(r, http.MethodPost, "/subscriptions", strings.Replace(body, `"price":0`, `"price":1`, 1), IdempotencyKeyHeader, "k1")
+	if other.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key: status %d, want 422", other.Code)
+	}
+}
+
+func TestDeleteAndRestoreSubscription(t *testing.T) {
+	r := newTestRouter(t)
+
+	w := serve(r, http.MethodPost, "/subscriptions", `{"service_name":"Netflix","user_id":"60601fee-2bf1-4721-ae6f-7636e79a0cba","start_date":"01-2025","price":500}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, body %s", w.Code, w.Body)
+	}
+	id := decodeSubscription(t, w).ID
+
+	steps := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodDelete, "/subscriptions/" + id, http.StatusNoContent},
+		{http.MethodGet, "/subscriptions/" + id, http.StatusNotFound},
+		{http.MethodDelete, "/subscriptions/" + id, http.StatusNotFound},
+		{http.MethodPost, "/subscriptions/" + id + "/restore", http.StatusOK},
+		{http.MethodGet, "/subscriptions/" + id, http.StatusOK},
+		{http.MethodPost, "/subscriptions/" + id + "/restore", http.StatusNotFound},
+	}
+	for _, step := range steps {
+		if w := serve(r, step.method, step.path, ""); w.Code != step.want {
+			t.Fatalf("%s %s: status %d, want %d; body %s", step.method, step.path, w.Code, step.want, w.Body)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/interfaces/http/dto"
+	"github.com/Neroframe/sub_crudl/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Health statuses reported by the probes.
+const (
+	healthOK          = "ok"
+	healthUnavailable = "unavailable"
+	healthDraining    = "shutting_down"
+)
+
+// defaultReadinessTimeout bounds the checks when no timeout is configured.
+const defaultReadinessTimeout = 2 * time.Second
+
+// ReadinessCheck is a dependency the API needs to serve requests. Fn returns
+// why it is not usable, or nil.
+type ReadinessCheck struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+// HealthHandler serves the liveness and readiness probes.
+type HealthHandler struct {
+	checks   []ReadinessCheck
+	timeout  time.Duration // for all checks of one probe together
+	draining atomic.Bool
+	log      *logger.Logger
+}
+
+func NewHealthHandler(timeout time.Duration, logger *logger.Logger, checks ...ReadinessCheck) *HealthHandler {
+	if timeout <= 0 {
+		timeout = defaultReadinessTimeout
+	}
+	return &HealthHandler{checks: checks, timeout: timeout, log: logger}
+}
+
+// Drain makes readiness fail from now on, so load balancers stop sending
+// traffic before the server shuts down.
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// Live godoc
+// @Summary     Liveness probe
+// @Description Succeeds while the process is up; it checks no dependencies.
+// @Tags        health
+// @Produce     json
+// @Success     200 {object} dto.HealthDTO
+// @Router      /healthz [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.HealthDTO{Status: healthOK})
+}
+
+// Ready godoc
+// @Summary     Readiness probe
+// @Description Succeeds when the database answers, its schema is at the version this build expects and the server is not shutting down.
+// @Tags        health
+// @Produce     json
+// @Success     200 {object} dto.HealthDTO
+// @Failure     503 {object} dto.HealthDTO "A dependency is unavailable, or the server is shutting down"
+// @Router      /readyz [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, dto.HealthDTO{Status: healthDraining})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	res := dto.HealthDTO{Status: healthOK, Checks: make(map[string]string, len(h.checks))}
+	for _, check := range h.checks {
+		if err := check.Fn(ctx); err != nil {
+			logger.FromContext(c.Request.Context(), h.log).Warn("readiness check failed", "check", check.Name, "error", err)
+			res.Status = healthUnavailable
+			res.Checks[check.Name] = healthUnavailable // the cause stays in the log
+			continue
+		}
+		res.Checks[check.Name] = healthOK
+	}
+
+	status := http.StatusOK
+	if res.Status != healthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, res)
+}
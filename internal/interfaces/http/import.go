@@ -0,0 +1,296 @@
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/interfaces/http/dto"
+	"github.com/Neroframe/sub_crudl/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+const (
+	csvContentType    = "text/csv"
+	ndjsonContentType = "application/x-ndjson"
+
+	importModeAtomic     = "atomic"
+	importModeBestEffort = "best_effort"
+
+	maxImportBytes = 10 << 20
+)
+
+type ImportResponse struct {
+	Mode    string             `json:"mode" example:"atomic"`
+	Created int                `json:"created" example:"2"`
+	Failed  int                `json:"failed" example:"1"`
+	Rows    []dto.ImportRowDTO `json:"rows"`
+}
+
+// importRow is one parsed record; err is set when it could not be decoded.
+type importRow struct {
+	input appdto.CreateInput
+	err   error
+}
+
+// ImportSubscriptions godoc
+// @Summary     Bulk import subscriptions
+// @Description Accepts CSV with a header row (service_name, start_date, price and optionally user_id, end_date, currency, billing_period) or NDJSON with one create payload per line.
+// @Description Every row is validated like POST /subscriptions. In atomic mode nothing is created unless all rows are valid; best_effort creates the valid rows and reports the rest.
+// @Description Rows are numbered from 1, not counting the CSV header or blank NDJSON lines.
+// @Tags        subscriptions
+// @Accept      text/csv
+// @Accept      application/x-ndjson
+// @Produce     json
+// @Param       mode query string false "atomic (default) or best_effort" Enums(atomic, best_effort)
+// @Param       body body string true "CSV or NDJSON document"
+// @Success     200 {object} httpapi.ImportResponse "best_effort report"
+// @Success     201 {object} httpapi.ImportResponse "all rows created"
+// @Failure     400 {object} httpapi.Problem
+// @Failure     401 {object} httpapi.Problem
+// @Failure     403 {object} httpapi.Problem
+// @Failure     413 {object} httpapi.Problem
+// @Failure     415 {object} httpapi.Problem
+// @Failure     422 {object} httpapi.ImportResponse "atomic import rejected"
+// @Failure     429 {object} httpapi.Problem "Rate limit exhausted, see Retry-After"
+// @Failure     500 {object} httpapi.Problem
+// @Security    BearerAuth
+// @Security    ApiKeyAuth
+// @Router      /subscriptions/import [post]
+func (h *Handler) ImportSubscriptions(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log).With("handler", "ImportSubscriptions")
+
+	mode := c.DefaultQuery("mode", importModeAtomic)
+	if mode != importModeAtomic && mode != importModeBestEffort {
+		writeProblem(c, log, malformed("mode", "mode must be atomic or best_effort"), "Invalid mode")
+		return
+	}
+
+	var parse func(io.Reader) ([]importRow, error)
+	switch c.ContentType() {
+	case csvContentType:
+		parse = parseCSVImport
+	case ndjsonContentType, "application/ndjson":
+		parse = parseNDJSONImport
+	default:
+		writeProblem(c, log, unsupportedMediaType("Content-Type must be text/csv or application/x-ndjson"), "Unsupported media type")
+		return
+	}
+
+	rows, err := parse(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = requestTooLarge(fmt.Sprintf("import body exceeds %d bytes", tooLarge.Limit))
+		} else {
+			err = malformed("", err.Error())
+		}
+		writeProblem(c, log, err, "Invalid import body")
+		return
+	}
+	if len(rows) == 0 {
+		writeProblem(c, log, malformed("", "no rows to import"), "Invalid import body")
+		return
+	}
+
+	resp := ImportResponse{Mode: mode, Rows: make([]dto.ImportRowDTO, len(rows))}
+	inputs := make([]appdto.CreateInput, 0, len(rows))
+	positions := make([]int, 0, len(rows)) // index into rows for each input
+	for i, row := range rows {
+		resp.Rows[i].Row = i + 1
+		if row.err != nil {
+			resp.Rows[i].Error = row.err.Error()
+			resp.Failed++
+			continue
+		}
+		inputs = append(inputs, row.input)
+		positions = append(positions, i)
+	}
+
+	atomic := mode == importModeAtomic
+	if atomic && resp.Failed > 0 {
+		log.Info("import rejected", "rows", len(rows), "failed", resp.Failed)
+		c.JSON(http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	if len(inputs) > 0 {
+		results, err := h.SubService.Import(c.Request.Context(), inputs, atomic)
+		if err != nil {
+			writeProblem(c, log, err, "Failed to import subscriptions")
+			return
+		}
+
+		for j, res := range results {
+			out := &resp.Rows[positions[j]]
+			switch {
+			case res.Err == nil:
+				if res.ID != uuid.Nil {
+					out.ID = res.ID.String()
+					resp.Created++
+				}
+			case errors.Is(res.Err, app.ErrInvalidInput), errors.Is(res.Err, app.ErrForbidden):
+				out.Error = res.Err.Error()
+				resp.Failed++
+			default:
+				out.Error = "Failed to create subscription"
+				resp.Failed++
+			}
+		}
+	}
+
+	log.Info("import finished", "mode", mode, "created", resp.Created, "failed", resp.Failed)
+	switch {
+	case !atomic:
+		c.JSON(http.StatusOK, resp)
+	case resp.Failed > 0:
+		c.JSON(http.StatusUnprocessableEntity, resp)
+	default:
+		c.JSON(http.StatusCreated, resp)
+	}
+}
+
+// csvImportColumns lists the accepted CSV header names.
+var csvImportColumns = map[string]bool{
+	"id":             false, // written by export, ignored on import
+	"service_name":   true,
+	"user_id":        false,
+	"start_date":     true,
+	"end_date":       false,
+	"price":          true,
+	"currency":       false,
+	"billing_period": false,
+}
+
+// parseCSVImport reads a CSV document whose first record names the columns.
+// Only a malformed document is an error; bad rows are reported per row.
+func parseCSVImport(r io.Reader) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := csvImportColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		if _, dup := index[name]; dup {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		index[name] = i
+	}
+	for name, required := range csvImportColumns {
+		if _, ok := index[name]; required && !ok {
+			return nil, fmt.Errorf("missing CSV column %q", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := index[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []importRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+
+		req := dto.CreateSubscriptionDTO{
+			ServiceName:   field(record, "service_name"),
+			UserID:        field(record, "user_id"),
+			StartDate:     field(record, "start_date"),
+			EndDate:       field(record, "end_date"),
+			Currency:      field(record, "currency"),
+			BillingPeriod: field(record, "billing_period"),
+		}
+		price, err := strconv.ParseInt(field(record, "price"), 10, 32)
+		if err != nil {
+			rows = append(rows, importRow{err: malformed("price", "price must be an integer")})
+			continue
+		}
+		p := int32(price)
+		req.Price = &p
+
+		rows = append(rows, decodeImportRow(req))
+	}
+}
+
+// csvError keeps body size errors intact so the handler can report 413.
+func csvError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	return fmt.Errorf("malformed CSV: %v", err)
+}
+
+// parseNDJSONImport reads one create payload per line, skipping blank lines.
+func parseNDJSONImport(r io.Reader) ([]importRow, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxImportBytes)
+
+	var rows []importRow
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var req dto.CreateSubscriptionDTO
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			rows = append(rows, importRow{err: malformed("", "row is not a valid JSON object")})
+			continue
+		}
+		rows = append(rows, decodeImportRow(req))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// decodeImportRow applies the same binding rules as POST /subscriptions.
+func decodeImportRow(req dto.CreateSubscriptionDTO) importRow {
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			return importRow{err: errors.New(validationDetail(ve[0]))}
+		}
+		return importRow{err: errors.New("invalid row")}
+	}
+
+	input, err := toCreateInput(req)
+	if err != nil {
+		return importRow{err: err}
+	}
+	return importRow{input: input}
+}
@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/Neroframe/sub_crudl/internal/interfaces/http/dto"
+)
+
+// toSubscriptionDTO renders a subscription the way clients send it: snake_case
+// keys and MM-YYYY dates.
+func toSubscriptionDTO(sub *domain.Subscription) dto.SubscriptionDTO {
+	out := dto.SubscriptionDTO{
+		ID:            sub.ID.String(),
+		ServiceName:   sub.ServiceName,
+		Price:         sub.Price,
+		Currency:      sub.Currency,
+		BillingPeriod: string(sub.BillingPeriod),
+		UserID:        sub.UserID.String(),
+		StartDate:     sub.StartDate.Format("01-2006"),
+		Version:       sub.Version,
+	}
+	if sub.EndDate != nil {
+		end := sub.EndDate.Format("01-2006")
+		out.EndDate = &end
+	}
+	if sub.DeletedAt != nil {
+		deleted := sub.DeletedAt.Format(time.RFC3339)
+		out.DeletedAt = &deleted
+	}
+	return out
+}
+
+// toCreateSubscriptionDTO renders a subscription as the create payload that
+// import accepts, so an NDJSON export can be imported again unchanged.
+func toCreateSubscriptionDTO(sub *domain.Subscription) dto.CreateSubscriptionDTO {
+	price := sub.Price
+	out := dto.CreateSubscriptionDTO{
+		ServiceName:   sub.ServiceName,
+		UserID:        sub.UserID.String(),
+		StartDate:     sub.StartDate.Format("01-2006"),
+		Price:         &price,
+		Currency:      sub.Currency,
+		BillingPeriod: string(sub.BillingPeriod),
+	}
+	if sub.EndDate != nil {
+		out.EndDate = sub.EndDate.Format("01-2006")
+	}
+	return out
+}
+
+func toSubscriptionDTOs(subs []*domain.Subscription) []dto.SubscriptionDTO {
+	out := make([]dto.SubscriptionDTO, 0, len(subs))
+	for _, sub := range subs {
+		out = append(out, toSubscriptionDTO(sub))
+	}
+	return out
+}
+
+func toEventDTO(e *domain.SubscriptionEvent) dto.SubscriptionEventDTO {
+	out := dto.SubscriptionEventDTO{
+		ID:             e.ID.String(),
+		SubscriptionID: e.SubscriptionID.String(),
+		Action:         string(e.Action),
+		Actor:          e.Actor,
+		CreatedAt:      e.CreatedAt.Format(time.RFC3339),
+	}
+	if e.Before != nil {
+		before := toSubscriptionDTO(e.Before)
+		out.Before = &before
+	}
+	if e.After != nil {
+		after := toSubscriptionDTO(e.After)
+		out.After = &after
+	}
+	return out
+}
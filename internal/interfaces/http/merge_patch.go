@@ -0,0 +1,92 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+	"github.com/Neroframe/sub_crudl/internal/domain"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+
+	// maxPatchBytes bounds a merge patch; a subscription is far smaller.
+	maxPatchBytes = 64 << 10
+)
+
+// parseMergePatch turns an RFC 7396 document into an UpdateInput. Absent
+// members are left unchanged and null removes a value, which only end_date
+// allows. Unknown and read-only members are rejected.
+func parseMergePatch(body []byte) (appdto.UpdateInput, error) {
+	var input appdto.UpdateInput
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil || doc == nil {
+		return input, malformed("", "merge patch must be a JSON object")
+	}
+
+	for field, raw := range doc {
+		isNull := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+		if isNull && field != "end_date" {
+			return input, malformed(field, field+" cannot be null")
+		}
+
+		switch field {
+		case "service_name":
+			var v string
+			if err := json.Unmarshal(raw, &v); err != nil || v == "" {
+				return input, malformed("service_name", "service_name must be a non-empty string")
+			}
+			input.ServiceName = &v
+		case "price":
+			var v int32
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return input, malformed("price", "price must be an integer")
+			}
+			input.Price = &v
+		case "currency":
+			var v string
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return input, malformed("currency", "currency must be a string")
+			}
+			input.Currency = &v
+		case "billing_period":
+			var v domain.BillingPeriod
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return input, malformed("billing_period", "billing_period must be a string")
+			}
+			input.BillingPeriod = &v
+		case "start_date":
+			t, err := parseMonth(raw)
+			if err != nil {
+				return input, malformed("start_date", "start_date must be in MM-YYYY format")
+			}
+			input.StartDate = &t
+		case "end_date":
+			if isNull {
+				input.ClearEndDate = true
+				continue
+			}
+			t, err := parseMonth(raw)
+			if err != nil {
+				return input, malformed("end_date", "end_date must be in MM-YYYY format")
+			}
+			input.EndDate = &t
+		default:
+			return input, malformed(field, field+" cannot be patched")
+		}
+	}
+
+	return input, nil
+}
+
+// parseMonth decodes a JSON string in MM-YYYY format.
+func parseMonth(raw json.RawMessage) (time.Time, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse("01-2006", s)
+}
@@ -0,0 +1,93 @@
+package httpapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	appdto "github.com/Neroframe/sub_crudl/internal/app/dto"
+)
+
+func TestParseMergePatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantField string // field of the expected error; "" when none is expected
+		check     func(t *testing.T, in appdto.UpdateInput)
+	}{
+		{
+			name: "absent members stay unchanged",
+			body: `{"price": 0}`,
+			check: func(t *testing.T, in appdto.UpdateInput) {
+				if in.Price == nil || *in.Price != 0 {
+					t.Errorf("Price = %v, want 0", in.Price)
+				}
+				if in.ServiceName != nil || in.StartDate != nil || in.EndDate != nil || in.Currency != nil || in.BillingPeriod != nil {
+					t.Errorf("absent members were set: %+v", in)
+				}
+				if in.ClearEndDate {
+					t.Error("ClearEndDate set without end_date")
+				}
+			},
+		},
+		{
+			name: "null end_date clears it",
+			body: `{"end_date": null}`,
+			check: func(t *testing.T, in appdto.UpdateInput) {
+				if !in.ClearEndDate || in.EndDate != nil {
+					t.Errorf("ClearEndDate = %v, EndDate = %v; want true, nil", in.ClearEndDate, in.EndDate)
+				}
+			},
+		},
+		{
+			name: "null end_date with padding",
+			body: `{"end_date":   null  }`,
+			check: func(t *testing.T, in appdto.UpdateInput) {
+				if !in.ClearEndDate {
+					t.Error("ClearEndDate = false, want true")
+				}
+			},
+		},
+		{
+			name: "end_date is parsed as MM-YYYY",
+			body: `{"end_date": "03-2026"}`,
+			check: func(t *testing.T, in appdto.UpdateInput) {
+				want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+				if in.EndDate == nil || !in.EndDate.Equal(want) || in.ClearEndDate {
+					t.Errorf("EndDate = %v, ClearEndDate = %v; want %v, false", in.EndDate, in.ClearEndDate, want)
+				}
+			},
+		},
+		{name: "null price", body: `{"price": null}`, wantField: "price"},
+		{name: "null service_name", body: `{"service_name": null}`, wantField: "service_name"},
+		{name: "null start_date", body: `{"start_date": null}`, wantField: "start_date"},
+		{name: "null currency", body: `{"currency": null}`, wantField: "currency"},
+		{name: "null billing_period", body: `{"billing_period": null}`, wantField: "billing_period"},
+		{name: "empty service_name", body: `{"service_name": ""}`, wantField: "service_name"},
+		{name: "bad end_date", body: `{"end_date": "2026-03"}`, wantField: "end_date"},
+		{name: "read-only member", body: `{"id": "x"}`, wantField: "id"},
+		{name: "unknown member", body: `{"colour": "red"}`, wantField: "colour"},
+		{name: "not an object", body: `[1]`, wantField: ""},
+		{name: "null document", body: `null`, wantField: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := parseMergePatch([]byte(tt.body))
+			if tt.check != nil {
+				if err != nil {
+					t.Fatalf("parseMergePatch: %v", err)
+				}
+				tt.check(t, input)
+				return
+			}
+
+			var reqErr *requestError
+			if !errors.As(err, &reqErr) {
+				t.Fatalf("err = %v, want a requestError", err)
+			}
+			if reqErr.field != tt.wantField {
+				t.Errorf("error field = %q, want %q", reqErr.field, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package httpapi
+
+import (
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	"github.com/Neroframe/sub_crudl/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ActorHeader names the caller recorded in the subscription audit trail.
+const ActorHeader = "X-Actor"
+
+// RequestIDHeader carries the ID that ties together the log lines of one
+// request. Clients may send their own; the response always echoes it.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware accepts the client's X-Request-ID or generates one, and
+// stores a logger tagged with it in the request context for handlers and
+// services to log through.
+func RequestIDMiddleware(log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+
+		reqLog := &logger.Logger{Logger: log.With("request_id", id)}
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), reqLog))
+		c.Next()
+	}
+}
+
+// AccessLogMiddleware writes one line per request once it has been served.
+// It runs after RequestIDMiddleware so the line carries the request ID, and
+// before gin.Recovery so requests that panic are logged with their 500.
+func AccessLogMiddleware(log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		reqLog := logger.FromContext(c.Request.Context(), log)
+		reqLog.Info("request served",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"bytes", c.Writer.Size(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so they
+// cannot forge log lines or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// ActorMiddleware stores the caller identity in the request context so the
+// service can attribute mutations. Falls back to the client IP.
+func ActorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := c.GetHeader(ActorHeader)
+		if actor == "" {
+			actor = c.ClientIP()
+		}
+		c.Request = c.Request.WithContext(app.WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
@@ -0,0 +1,185 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// problemContentType is the media type of RFC 7807 error documents.
+const problemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 document every error response carries. Code is a
+// stable identifier for clients to branch on; Field names the offending
+// request field when the error is about one.
+type Problem struct {
+	Type   string `json:"type" example:"about:blank"`
+	Title  string `json:"title" example:"Unprocessable Entity"`
+	Status int    `json:"status" example:"422"`
+	Detail string `json:"detail,omitempty" example:"price must not be negative"`
+	Code   string `json:"code" example:"invalid_input"`
+	Field  string `json:"field,omitempty" example:"price"`
+}
+
+// Problem codes. A request that cannot be parsed is malformed (400); one that
+// parses but breaks a rule fails validation or is invalid input (422).
+const (
+	CodeMalformedRequest     = "malformed_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeInvalidInput         = "invalid_input"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeConflict             = "conflict"
+	CodePreconditionFailed   = "precondition_failed"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodeRequestTooLarge      = "request_too_large"
+	CodeRateLimited          = "rate_limited"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeInternal             = "internal_error"
+)
+
+func init() {
+	// Report validation failures under the JSON field names clients send
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// requestError is a problem a handler finds before calling the service.
+type requestError struct {
+	status int
+	code   string
+	field  string
+	detail string
+}
+
+func (e *requestError) Error() string { return e.detail }
+
+// malformed reports a request value that could not be parsed.
+func malformed(field, detail string) error {
+	return &requestError{status: http.StatusBadRequest, code: CodeMalformedRequest, field: field, detail: detail}
+}
+
+func unsupportedMediaType(detail string) error {
+	return &requestError{status: http.StatusUnsupportedMediaType, code: CodeUnsupportedMediaType, detail: detail}
+}
+
+func requestTooLarge(detail string) error {
+	return &requestError{status: http.StatusRequestEntityTooLarge, code: CodeRequestTooLarge, detail: detail}
+}
+
+// unauthorized reports a request whose caller could not be authenticated.
+func unauthorized(detail string) error {
+	return &requestError{status: http.StatusUnauthorized, code: CodeUnauthorized, field: "Authorization", detail: detail}
+}
+
+// forbidden reports a caller lacking the scope a route requires.
+func forbidden(detail string) error {
+	return &requestError{status: http.StatusForbidden, code: CodeForbidden, detail: detail}
+}
+
+// tooManyRequests reports a caller that has spent its rate limit budget.
+func tooManyRequests(detail string) error {
+	return &requestError{status: http.StatusTooManyRequests, code: CodeRateLimited, detail: detail}
+}
+
+func preconditionFailed(detail string) error {
+	return &requestError{status: http.StatusPreconditionFailed, code: CodePreconditionFailed, field: "If-Match", detail: detail}
+}
+
+// bindError classifies an error from binding a JSON body: rule violations
+// are kept for the translator, anything else means the body did not decode.
+func bindError(err error) error {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		return ve
+	}
+	return malformed("", "request body is not a valid JSON object for this endpoint")
+}
+
+// validationDetail describes the first failed rule of a binding error.
+func validationDetail(fe validator.FieldError) string {
+	return fmt.Sprintf("%s failed %s validation", fe.Field(), fe.Tag())
+}
+
+// toProblem translates err into the document sent to the client. It reports
+// false for errors it does not recognise, which are internal.
+func toProblem(err error) (Problem, bool) {
+	var reqErr *requestError
+	var ve validator.ValidationErrors
+	var fieldErr *app.FieldError
+	switch {
+	case errors.As(err, &reqErr):
+		return newProblem(reqErr.status, reqErr.code, reqErr.field, reqErr.detail), true
+	case errors.As(err, &ve) && len(ve) > 0:
+		return newProblem(http.StatusUnprocessableEntity, CodeValidationFailed, ve[0].Field(), validationDetail(ve[0])), true
+	case errors.As(err, &fieldErr):
+		return newProblem(http.StatusUnprocessableEntity, CodeInvalidInput, fieldErr.Field, fieldErr.Field+" "+fieldErr.Reason), true
+	case errors.Is(err, app.ErrInvalidInput):
+		return newProblem(http.StatusUnprocessableEntity, CodeInvalidInput, "", err.Error()), true
+	case errors.Is(err, app.ErrNotFound):
+		return newProblem(http.StatusNotFound, CodeNotFound, "", app.ErrNotFound.Error()), true
+	case errors.Is(err, app.ErrAPIKeyNotFound):
+		return newProblem(http.StatusNotFound, CodeNotFound, "", app.ErrAPIKeyNotFound.Error()), true
+	case errors.Is(err, app.ErrUnauthenticated):
+		return newProblem(http.StatusUnauthorized, CodeUnauthorized, "", app.ErrUnauthenticated.Error()), true
+	case errors.Is(err, app.ErrForbidden):
+		return newProblem(http.StatusForbidden, CodeForbidden, "", forbiddenDetail(err)), true
+	case errors.Is(err, app.ErrConflict):
+		return newProblem(http.StatusConflict, CodeConflict, "", app.ErrConflict.Error()), true
+	case errors.Is(err, app.ErrIdempotencyKeyReused):
+		return newProblem(http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, IdempotencyKeyHeader, app.ErrIdempotencyKeyReused.Error()), true
+	}
+	return Problem{}, false
+}
+
+// forbiddenDetail explains an ErrForbidden, naming the role and action when
+// the access policy refused it.
+func forbiddenDetail(err error) string {
+	var policyErr *app.PolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Error()
+	}
+	return app.ErrForbidden.Error()
+}
+
+func newProblem(status int, code, field, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Field:  field,
+	}
+}
+
+// writeProblem logs err and responds with its problem document. Unrecognised
+// errors become a 500 with fallback as the detail, so internals stay out of
+// responses.
+func writeProblem(c *gin.Context, log *slog.Logger, err error, fallback string) {
+	p, ok := toProblem(err)
+	if ok {
+		log.Info("request rejected", "status", p.Status, "code", p.Code, "error", err)
+	} else {
+		log.Error("request failed", "error", err)
+		p = newProblem(http.StatusInternalServerError, CodeInternal, "", fallback)
+	}
+	c.Header("Content-Type", problemContentType)
+	c.JSON(p.Status, p)
+}
@@ -0,0 +1,108 @@
+package httpapi
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Neroframe/sub_crudl/internal/app"
+	"github.com/Neroframe/sub_crudl/pkg/logger"
+	"github.com/Neroframe/sub_crudl/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RateLimiter gives each client separate budgets for reads, writes and cost
+// reports, which are the most expensive queries, on top of a budget per
+// client IP that is spent before authentication. A nil limiter leaves its
+// kind of request unlimited.
+type RateLimiter struct {
+	ip        *ratelimit.Limiter
+	read      *ratelimit.Limiter
+	write     *ratelimit.Limiter
+	aggregate *ratelimit.Limiter
+	log       *logger.Logger
+}
+
+func NewRateLimiter(ip, read, write, aggregate *ratelimit.Limiter, logger *logger.Logger) *RateLimiter {
+	return &RateLimiter{ip: ip, read: read, write: write, aggregate: aggregate, log: logger}
+}
+
+// PerIP counts every request against the budget of its client IP. It runs
+// before authentication, so callers with bad credentials are throttled
+// before each attempt costs a key lookup.
+func (l *RateLimiter) PerIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.ip != nil && !l.allow(c, l.ip, "ip:"+c.ClientIP(), "ip") {
+			return
+		}
+		c.Next()
+	}
+}
+
+// Middleware counts the request against the caller's budget and answers 429
+// once it is spent. It runs after authentication so callers are told apart
+// by API key or token subject within their tenant. Every response carries
+// the RateLimit-* headers of the last budget it was counted against.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiter, budget := l.pick(c)
+		if limiter != nil && !l.allow(c, limiter, clientKey(c), budget) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// allow spends a token of client from limiter, or answers 429 and aborts.
+func (l *RateLimiter) allow(c *gin.Context, limiter *ratelimit.Limiter, client, budget string) bool {
+	res := limiter.Allow(client)
+	h := c.Writer.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", seconds(res.Reset))
+	if !res.Allowed {
+		log := logger.FromContext(c.Request.Context(), l.log).With("middleware", "RateLimiter", "client", client, "budget", budget)
+		h.Set("Retry-After", seconds(res.RetryAfter))
+		writeProblem(c, log, tooManyRequests(fmt.Sprintf("the %s rate limit is exhausted", budget)), "Too many requests")
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// pick returns the budget a request is counted against.
+func (l *RateLimiter) pick(c *gin.Context) (*ratelimit.Limiter, string) {
+	switch {
+	case strings.HasPrefix(c.FullPath(), "/subscriptions/aggregate"):
+		return l.aggregate, "aggregate"
+	case c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead:
+		return l.read, "read"
+	}
+	return l.write, "write"
+}
+
+// clientKey identifies the caller the budget belongs to. Callers without a
+// key or subject, such as everyone when authentication is disabled, are
+// keyed by ClientIP, which only honours X-Forwarded-For from the engine's
+// trusted proxies.
+func clientKey(c *gin.Context) string {
+	ctx := c.Request.Context()
+	p, ok := app.PrincipalFromContext(ctx)
+	tenant, _ := app.TenantFromContext(ctx)
+	switch {
+	case ok && p.APIKey != nil:
+		return "key:" + tenant + ":" + p.APIKey.ID.String()
+	case ok && p.UserID != uuid.Nil:
+		return "user:" + tenant + ":" + p.UserID.String()
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds renders d as whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -1,16 +1,40 @@
 package httpapi
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/Neroframe/sub_crudl/internal/domain"
+	"github.com/gin-gonic/gin"
+)
 
-func RegisterRoutes(r *gin.Engine, h *Handler) {
-	api := r.Group("/subscriptions")
+func RegisterRoutes(r *gin.Engine, h *Handler, keys *APIKeyHandler, auth *Authenticator, limits *RateLimiter, health *HealthHandler) {
+	// Probes stay outside authentication and rate limits
+	r.GET("/healthz", health.Live)
+	r.GET("/readyz", health.Ready)
+
+	read := auth.Require(domain.ScopeRead)
+	write := auth.Require(domain.ScopeWrite)
+	aggregate := auth.Require(domain.ScopeAggregate)
+
+	api := r.Group("/subscriptions", ActorMiddleware(), limits.PerIP(), auth.Middleware(), limits.Middleware())
 	{
-		api.POST("", h.CreateSubscription)
-		api.GET("", h.ListSubscriptions)
-		api.GET("/:id", h.GetSubscription)
-		api.PUT("/:id", h.UpdateSubscription)
-		api.DELETE("/:id", h.DeleteSubscription)
+		api.POST("", write, h.CreateSubscription)
+		api.GET("", read, h.ListSubscriptions)
+		api.POST("/import", write, h.ImportSubscriptions)
+		api.GET("/export", read, h.ExportSubscriptions)
+		api.GET("/trash", read, h.ListDeletedSubscriptions)
+		api.GET("/:id", read, h.GetSubscription)
+		api.PUT("/:id", write, h.UpdateSubscription)
+		api.PATCH("/:id", write, h.PatchSubscription)
+		api.DELETE("/:id", write, h.DeleteSubscription)
+		api.POST("/:id/restore", write, h.RestoreSubscription)
+		api.GET("/:id/history", read, h.GetSubscriptionHistory)
+		api.GET("/aggregate", aggregate, h.AggregateSubscriptions)
+		api.GET("/aggregate/timeseries", aggregate, h.AggregateTimeSeries)
 	}
 
-	r.GET("/subscriptions/aggregate", h.AggregateSubscriptions)
+	admin := r.Group("/api-keys", limits.PerIP(), auth.Middleware(), limits.Middleware(), auth.Require(domain.ScopeAdmin))
+	{
+		admin.POST("", keys.IssueAPIKey)
+		admin.GET("", keys.ListAPIKeys)
+		admin.DELETE("/:id", keys.RevokeAPIKey)
+	}
 }
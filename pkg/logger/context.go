@@ -0,0 +1,19 @@
+package logger
+
+import "context"
+
+type ctxKey struct{}
+
+// WithContext attaches l to ctx, so code serving one request logs with the
+// attributes of that request.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger attached to ctx, or fallback without one.
+func FromContext(ctx context.Context, fallback *Logger) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return l
+	}
+	return fallback
+}
@@ -0,0 +1,104 @@
+// Package ratelimit keeps one token bucket per client key. Each bucket holds
+// up to Burst tokens and refills at a steady rate; a request spends one.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that have refilled completely are
+// dropped; a full bucket is the same as none.
+const sweepInterval = time.Minute
+
+// Limiter rations requests per key.
+type Limiter struct {
+	rate  float64 // tokens per second
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time // when tokens was last brought up to date
+}
+
+// Result describes a bucket after a request was counted against it.
+type Result struct {
+	Allowed    bool
+	Limit      int           // bucket size
+	Remaining  int           // whole tokens left
+	RetryAfter time.Duration // until a request would be allowed; 0 if it was
+	Reset      time.Duration // until the bucket is full again
+}
+
+// New returns a limiter allowing perMinute requests a minute per key, with
+// bursts of up to burst requests. A burst below 1 is raised to 1.
+func New(perMinute, burst int) *Limiter {
+	return &Limiter{
+		rate:    float64(perMinute) / 60,
+		burst:   max(burst, 1),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow spends a token from the bucket of key, if it has one.
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), at: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.at = now
+
+	res := Result{Limit: l.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.wait(1 - b.tokens)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = l.wait(float64(l.burst) - b.tokens)
+	return res
+}
+
+// refill returns the tokens b holds at now.
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	return math.Min(float64(l.burst), b.tokens+now.Sub(b.at).Seconds()*l.rate)
+}
+
+// wait is how long it takes to refill n tokens.
+func (l *Limiter) wait(n float64) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	if l.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(n / l.rate * float64(time.Second))
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}